@@ -0,0 +1,194 @@
+package sse
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultAckBatchSize     = 1
+	defaultAckBatchInterval = time.Second
+)
+
+// WithAckEndpoint acknowledges every dispatched event that has an ID by POSTing {"id": "<id>"} to url.
+// Events without an id field of their own aren't acknowledged.
+//
+// Acknowledgments are sent one per line in a single request when batched with WithAckBatch.
+// They're sent in the background, so a slow endpoint doesn't hold up the stream.
+func WithAckEndpoint(url string) Option {
+	return func(s *Stream) {
+		s.ackEndpoint = url
+	}
+}
+
+// WithAckBatch sends acknowledgments once size are pending or interval has elapsed since the first pending acknowledgment.
+// size must be at least 1.
+func WithAckBatch(size int, interval time.Duration) Option {
+	return func(s *Stream) {
+		if size < 1 {
+			s.optionErr = errors.Errorf("invalid ack batch size %d", size)
+			return
+		}
+		s.ackBatchSize = size
+		s.ackBatchInterval = interval
+	}
+}
+
+// WithAckOnSuccess sets a function called for every event ID that was acknowledged
+func WithAckOnSuccess(fn func(id string)) Option {
+	return func(s *Stream) {
+		s.ackOnSuccess = fn
+	}
+}
+
+// WithAckOnFailure sets a function called for every event ID that couldn't be acknowledged
+func WithAckOnFailure(fn func(id string, err error)) Option {
+	return func(s *Stream) {
+		s.ackOnFailure = fn
+	}
+}
+
+type acker struct {
+	endpoint   string
+	httpClient *http.Client
+	batchSize  int
+	interval   time.Duration
+	clock      Clock
+	onSuccess  func(id string)
+	onFailure  func(id string, err error)
+
+	mu      sync.Mutex
+	pending []string
+	closed  bool
+	// wake is signalled when an acknowledgment is pending or the acker is closed
+	wake chan struct{}
+	done chan struct{}
+}
+
+func (s Stream) newAcker() *acker {
+	a := &acker{
+		endpoint:   s.ackEndpoint,
+		httpClient: s.httpClient,
+		batchSize:  s.ackBatchSize,
+		interval:   s.ackBatchInterval,
+		clock:      s.clock,
+		onSuccess:  s.ackOnSuccess,
+		onFailure:  s.ackOnFailure,
+		wake:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// ack queues an acknowledgment without waiting for it to be sent
+func (a *acker) ack(id string) {
+	a.mu.Lock()
+	a.pending = append(a.pending, id)
+	a.mu.Unlock()
+	a.signal()
+}
+
+// close sends any pending acknowledgments and waits for them to complete
+func (a *acker) close() {
+	a.mu.Lock()
+	a.closed = true
+	a.mu.Unlock()
+	a.signal()
+	<-a.done
+}
+
+func (a *acker) signal() {
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (a *acker) run() {
+	defer close(a.done)
+
+	var timer <-chan time.Time
+	for {
+		var expired bool
+		select {
+		case <-a.wake:
+		case <-timer:
+			expired = true
+		}
+
+		a.mu.Lock()
+		pending, closed := a.pending, a.closed
+		if expired || closed || len(pending) >= a.batchSize {
+			a.pending = nil
+		} else {
+			pending = nil
+		}
+		a.mu.Unlock()
+
+		sent := len(pending) != 0
+		for len(pending) > 0 {
+			n := min(len(pending), a.batchSize)
+			a.send(pending[:n])
+			pending = pending[n:]
+		}
+		if closed {
+			return
+		}
+
+		a.mu.Lock()
+		waiting := len(a.pending) != 0
+		a.mu.Unlock()
+		switch {
+		case !waiting:
+			timer = nil
+		case timer == nil || sent:
+			// The interval starts again from the first acknowledgment that's still pending
+			timer = a.clock.After(a.interval)
+		}
+	}
+}
+
+func (a *acker) send(ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+
+	err := a.post(ids)
+	for _, id := range ids {
+		if err != nil && a.onFailure != nil {
+			a.onFailure(id, err)
+		}
+		if err == nil && a.onSuccess != nil {
+			a.onSuccess(id)
+		}
+	}
+}
+
+func (a *acker) post(ids []string) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, id := range ids {
+		if err := encoder.Encode(struct {
+			ID string `json:"id"`
+		}{id}); err != nil {
+			return errors.Wrap(err, "encoding ack")
+		}
+	}
+
+	resp, err := a.httpClient.Post(a.endpoint, "application/json", &body)
+	if err != nil {
+		return errors.Wrap(err, "http error")
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+	return nil
+}
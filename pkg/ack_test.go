@@ -0,0 +1,126 @@
+package sse
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithAckEndpoint(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("id: 1\ndata: a\n\nid: 2\ndata: b\n\nid: 3\ndata: c\n\n"))
+	})
+	mux.HandleFunc("/ack", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var acked []string
+	s, err := New(server.URL+"/events",
+		WithAckEndpoint(server.URL+"/ack"),
+		WithAckBatch(2, time.Hour),
+		WithAckOnSuccess(func(id string) { acked = append(acked, id) }),
+		WithMaxReconnectAttempts(0),
+	)
+	require.NoError(err)
+
+	for range s.Events() {
+	}
+
+	assert.Equal([]string{"1", "2", "3"}, acked)
+	assert.Equal([]string{"{\"id\":\"1\"}\n{\"id\":\"2\"}\n", "{\"id\":\"3\"}\n"}, bodies)
+}
+
+func TestWithAckOnFailure(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: no id\n\nid: 1\ndata: a\n\n"))
+	})
+	mux.HandleFunc("/ack", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var failed []string
+	s, err := New(server.URL+"/events",
+		WithAckEndpoint(server.URL+"/ack"),
+		WithAckOnFailure(func(id string, err error) {
+			assert.Error(t, err)
+			failed = append(failed, id)
+		}),
+		WithMaxReconnectAttempts(0),
+	)
+	require.NoError(t, err)
+
+	for range s.Events() {
+	}
+
+	assert.Equal(t, []string{"1"}, failed)
+}
+
+func TestWithAckEndpointSlow(t *testing.T) {
+	release := make(chan struct{})
+	var (
+		mu    sync.Mutex
+		acked []string
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("id: 1\ndata: a\n\ndata: inherits id 1\n\nid: 2\ndata: b\n\nid: 3\ndata: c\n\n"))
+	})
+	mux.HandleFunc("/ack", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	s, err := New(server.URL+"/events",
+		WithAckEndpoint(server.URL+"/ack"),
+		WithAckOnSuccess(func(id string) {
+			mu.Lock()
+			acked = append(acked, id)
+			mu.Unlock()
+		}),
+		WithMaxReconnectAttempts(0),
+	)
+	require.NoError(t, err)
+
+	// Every event is dispatched while the first acknowledgment is still in flight
+	for i := 0; i < 4; i++ {
+		<-s.Events()
+	}
+	close(release)
+	for range s.Events() {
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"1", "2", "3"}, acked, "events without an id field aren't acknowledged")
+}
+
+func TestWithAckBatchInvalid(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		_, err := New("http://localhost", WithAckEndpoint("http://localhost/ack"), WithAckBatch(size, time.Second))
+		assert.EqualError(t, err, "invalid ack batch size "+strconv.Itoa(size))
+	}
+}
@@ -0,0 +1,105 @@
+// Package binary implements a compact length-prefixed framing for events as an alternative to the event stream format.
+//
+// Each frame is a 4 byte big-endian length followed by the event type, a NUL byte, and the event data.
+package binary
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/pkg/errors"
+)
+
+// MaxFrameSize is the largest frame BinaryDecoder accepts
+const MaxFrameSize = 1 << 24
+
+// ErrFrameTooLarge is returned when a frame is larger than MaxFrameSize
+var ErrFrameTooLarge = errors.New("frame too large")
+
+// BinaryEncoder writes events as binary frames
+type BinaryEncoder struct {
+	w io.Writer
+}
+
+// NewBinaryEncoder constructs a BinaryEncoder writing to w
+func NewBinaryEncoder(w io.Writer) *BinaryEncoder {
+	return &BinaryEncoder{w: w}
+}
+
+// Encode writes an event as a single frame
+func (e *BinaryEncoder) Encode(event sse.Event) error {
+	if strings.IndexByte(event.Type, 0) != -1 {
+		return errors.New("event type contains NUL")
+	}
+
+	size := len(event.Type) + 1 + len(event.Data)
+	if size > MaxFrameSize {
+		return ErrFrameTooLarge
+	}
+
+	frame := make([]byte, 4, 4+size)
+	binary.BigEndian.PutUint32(frame, uint32(size))
+	frame = append(frame, event.Type...)
+	frame = append(frame, 0)
+	frame = append(frame, event.Data...)
+
+	_, err := e.w.Write(frame)
+	return errors.Wrap(err, "writing frame")
+}
+
+// WriteEvent writes an event to w as a single frame
+func WriteEvent(w io.Writer, e sse.Event) error {
+	return NewBinaryEncoder(w).Encode(e)
+}
+
+// BinaryDecoder reads events from binary frames.
+// It implements sse.Decoder.
+type BinaryDecoder struct {
+	r io.Reader
+}
+
+// NewBinaryDecoder constructs a BinaryDecoder reading from r
+func NewBinaryDecoder(r io.Reader) *BinaryDecoder {
+	return &BinaryDecoder{r: r}
+}
+
+// Decode reads the next frame, returning io.EOF if there are no more frames
+func (d *BinaryDecoder) Decode() (sse.Event, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(d.r, header[:]); err != nil {
+		if err == io.EOF {
+			return sse.Event{}, err
+		}
+		return sse.Event{}, errors.Wrap(err, "reading frame length")
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > MaxFrameSize {
+		return sse.Event{}, ErrFrameTooLarge
+	}
+
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(d.r, frame); err != nil {
+		return sse.Event{}, errors.Wrap(err, "reading frame")
+	}
+
+	i := bytes.IndexByte(frame, 0)
+	if i == -1 {
+		return sse.Event{}, errors.New("frame missing NUL separator")
+	}
+
+	return sse.Event{
+		Type: string(frame[:i]),
+		Data: string(frame[i+1:]),
+	}, nil
+}
+
+// WithBinaryDecoder parses the resource of a Stream as binary frames
+func WithBinaryDecoder() sse.Option {
+	return sse.WithDecoder(func(r io.Reader) sse.Decoder {
+		return NewBinaryDecoder(r)
+	})
+}
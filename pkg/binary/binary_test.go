@@ -0,0 +1,65 @@
+package binary
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinary(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	events := []sse.Event{
+		{Type: "message", Data: "foo"},
+		{Type: "score", Data: "multi\nline"},
+		{Type: "message", Data: ""},
+	}
+
+	var buf bytes.Buffer
+	for _, event := range events {
+		require.NoError(WriteEvent(&buf, event))
+	}
+	assert.Equal([]byte{0, 0, 0, 11, 'm', 'e', 's', 's', 'a', 'g', 'e', 0, 'f', 'o', 'o'}, buf.Bytes()[:15])
+
+	decoder := NewBinaryDecoder(&buf)
+	var decoded []sse.Event
+	for {
+		event, err := decoder.Decode()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(err)
+		decoded = append(decoded, event)
+	}
+	assert.Equal(events, decoded)
+}
+
+func TestBinaryDecoderTruncated(t *testing.T) {
+	_, err := NewBinaryDecoder(bytes.NewReader([]byte{0, 0, 0, 4, 'a', 0})).Decode()
+	assert.Error(t, err)
+	assert.NotEqual(t, io.EOF, err)
+}
+
+func TestWithBinaryDecoder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteEvent(w, sse.Event{Type: "score", Data: "1"})
+		WriteEvent(w, sse.Event{Type: "score", Data: "2"})
+	}))
+	defer server.Close()
+
+	s, err := sse.New(server.URL, WithBinaryDecoder(), sse.WithMaxReconnectAttempts(0))
+	require.NoError(t, err)
+
+	var events []sse.Event
+	for event := range s.Events() {
+		events = append(events, event)
+	}
+	assert.Equal(t, []sse.Event{{Type: "score", Data: "1"}, {Type: "score", Data: "2"}}, events)
+}
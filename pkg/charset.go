@@ -0,0 +1,41 @@
+package sse
+
+import (
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/transform"
+)
+
+// WithCharsetDetection decodes responses whose Content-Type has a charset other than UTF-8, such as ISO-8859-1 or
+// windows-1252, to UTF-8 before parsing them.
+//
+// Event streams must be encoded as UTF-8, but some legacy servers don't. A warning is logged to the logger set by
+// WithLogger for each response that isn't, and responses with an unknown charset are parsed as UTF-8.
+func WithCharsetDetection() Option {
+	return func(s *Stream) {
+		s.charsetDetection = true
+	}
+}
+
+// decodeCharset returns body decoded to UTF-8 from the charset of resp
+func (s Stream) decodeCharset(resp *http.Response, body io.ReadCloser) io.ReadCloser {
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || params["charset"] == "" {
+		return body
+	}
+	encoding, name := charset.Lookup(params["charset"])
+	if encoding == nil || name == "utf-8" {
+		return body
+	}
+	s.warn("stream isn't utf-8", slog.String("resource", resp.Request.URL.String()), slog.String("charset", name))
+	return decodingReader{transform.NewReader(body, encoding.NewDecoder()), body}
+}
+
+type decodingReader struct {
+	io.Reader
+	io.Closer
+}
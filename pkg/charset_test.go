@@ -0,0 +1,46 @@
+package sse
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCharsetDetection(t *testing.T) {
+	for _, contentType := range []string{
+		"text/event-stream; charset=ISO-8859-1",
+		"text/event-stream; charset=windows-1252",
+	} {
+		t.Run(contentType, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", contentType)
+				w.Write([]byte("data: caf\xe9 \x80\n\n"))
+			}))
+			defer server.Close()
+
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&logs, nil))
+			s, err := New(server.URL, WithCharsetDetection(), WithMaxReconnectAttempts(0), WithLogger(logger))
+			require.NoError(t, err)
+			assert.Equal(t, "café €", (<-s.Events()).Data)
+			assert.Contains(t, logs.String(), `level=WARN msg="stream isn't utf-8" resource=`+server.URL+" charset=windows-1252")
+		})
+	}
+}
+
+func TestWithCharsetDetectionUTF8(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		w.Write([]byte("data: café\n\n"))
+	}))
+	defer server.Close()
+
+	s, err := New(server.URL, WithCharsetDetection(), WithMaxReconnectAttempts(0))
+	require.NoError(t, err)
+	assert.Equal(t, "café", (<-s.Events()).Data)
+}
@@ -0,0 +1,90 @@
+package sse
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrCircuitOpen closes a stream whose circuit breaker opened after too many failed reconnection attempts
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitState represents the state of a stream's circuit breaker
+type CircuitState int32
+
+const (
+	// CircuitClosed means the stream is connected or reconnecting as usual.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen means reconnection attempts failed too many times in a row, and the stream is waiting for the cooldown
+	// to elapse, or has closed if there isn't one.
+	CircuitOpen
+	// CircuitHalfOpen means the stream is probing the resource with a single attempt after the cooldown.
+	CircuitHalfOpen
+)
+
+func (c CircuitState) String() string {
+	switch c {
+	case CircuitClosed:
+		return "CLOSED"
+	case CircuitOpen:
+		return "OPEN"
+	case CircuitHalfOpen:
+		return "HALF-OPEN"
+	}
+	return "UNKNOWN"
+}
+
+// WithCircuitBreaker stops reconnecting after failures consecutive failed reconnection attempts.
+//
+// Without a cooldown the stream then closes with ErrCircuitOpen. Otherwise it waits for the cooldown before probing the
+// resource with a single attempt, closing the circuit if it connects and waiting for the cooldown again if it doesn't.
+// The state is reported by Stream.CircuitState.
+func WithCircuitBreaker(failures int, cooldown time.Duration) Option {
+	return func(s *Stream) {
+		if failures < 1 {
+			s.optionErr = errors.Errorf("invalid circuit breaker failures %d", failures)
+			return
+		}
+		s.circuit = &circuitBreaker{failures: failures, cooldown: cooldown}
+	}
+}
+
+// CircuitState returns the state of the stream's circuit breaker, which is always CircuitClosed without WithCircuitBreaker
+func (s Stream) CircuitState() CircuitState {
+	if s.circuit == nil {
+		return CircuitClosed
+	}
+	return CircuitState(s.circuit.state.Load())
+}
+
+// circuitBreaker is shared between copies of a Stream
+type circuitBreaker struct {
+	failures int
+	cooldown time.Duration
+	state    atomic.Int32
+}
+
+// delay returns how long to wait before the next attempt after failures consecutive failed attempts,
+// or a negative duration if the circuit opened and the stream must close
+func (c *circuitBreaker) delay(failures int, interval time.Duration) time.Duration {
+	if CircuitState(c.state.Load()) != CircuitHalfOpen && failures < c.failures {
+		return interval
+	}
+
+	c.state.Store(int32(CircuitOpen))
+	if c.cooldown <= 0 {
+		return -1
+	}
+	return c.cooldown
+}
+
+// probe half opens the circuit for an attempt after the cooldown
+func (c *circuitBreaker) probe() {
+	c.state.CompareAndSwap(int32(CircuitOpen), int32(CircuitHalfOpen))
+}
+
+// reset closes the circuit once the stream connects
+func (c *circuitBreaker) reset() {
+	c.state.Store(int32(CircuitClosed))
+}
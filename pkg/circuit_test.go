@@ -0,0 +1,95 @@
+package sse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCircuitBreaker(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var connections atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if connections.Add(1) > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("retry: 1\ndata: a\n\n"))
+	}))
+	defer server.Close()
+
+	s, err := New(server.URL, WithCircuitBreaker(2, 0))
+	require.NoError(err)
+	assert.Equal(CircuitClosed, s.CircuitState())
+	for range s.Events() {
+	}
+
+	var errs []error
+	for err := range s.Errors() {
+		errs = append(errs, err)
+	}
+	require.NotEmpty(errs)
+	assert.ErrorIs(errs[len(errs)-1], ErrCircuitOpen)
+	assert.Equal(int32(3), connections.Load())
+	assert.Equal(CircuitOpen, s.CircuitState())
+	assert.Equal(Closed, s.State())
+}
+
+func TestWithCircuitBreakerCooldown(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var healthy atomic.Bool
+	healthy.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("retry: 1\ndata: a\n\n"))
+		healthy.Store(false)
+	}))
+	defer server.Close()
+
+	c := NewMockClock(time.Now())
+	s, err := New(server.URL, WithClock(c), WithCircuitBreaker(2, time.Minute))
+	require.NoError(err)
+	assert.Equal("a", (<-s.Events()).Data)
+
+	type step struct {
+		delay time.Duration
+		state CircuitState
+	}
+	// Two failed attempts open the circuit, then the probe after the cooldown fails and the next one connects
+	for i, step := range []step{
+		{time.Millisecond, CircuitClosed},
+		{2 * time.Millisecond, CircuitClosed},
+		{time.Minute, CircuitOpen},
+		{time.Minute, CircuitOpen},
+	} {
+		reconnect := <-s.Reconnects()
+		assert.Equal(step.delay, reconnect.Delay)
+		assert.Equal(step.state, s.CircuitState())
+		require.Eventually(func() bool { return c.Waiters() == 1 }, time.Second, time.Millisecond)
+		if i == 3 {
+			healthy.Store(true)
+		}
+		c.Advance(step.delay)
+	}
+
+	assert.Equal("a", (<-s.Events()).Data)
+	assert.Equal(CircuitClosed, s.CircuitState())
+	require.NoError(s.Close())
+}
+
+func TestWithCircuitBreakerInvalid(t *testing.T) {
+	_, err := New("http://localhost", WithCircuitBreaker(0, 0))
+	assert.EqualError(t, err, "invalid circuit breaker failures 0")
+}
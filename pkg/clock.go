@@ -0,0 +1,138 @@
+package sse
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time and waits for durations to elapse, for streams to measure time with
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// After returns a channel receiving the current time once d has elapsed
+	After(d time.Duration) <-chan time.Time
+	// Sleep blocks until d has elapsed
+	Sleep(d time.Duration)
+}
+
+// WithClock measures time with c, for reconnection delays, timeouts, batching windows and statistics.
+// The default is RealClock.
+func WithClock(c Clock) Option {
+	return func(s *Stream) {
+		s.clock = c
+	}
+}
+
+// RealClock is the Clock of the time package
+type RealClock struct{}
+
+// Now returns time.Now()
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// After returns time.After(d)
+func (RealClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Sleep calls time.Sleep(d)
+func (RealClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// MockClock is a Clock whose time only moves when it's advanced, for deterministic tests
+type MockClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []mockWaiter
+}
+
+type mockWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewMockClock constructs a MockClock set to now
+func NewMockClock(now time.Time) *MockClock {
+	return &MockClock{now: now}
+}
+
+// Now returns the clock's time
+func (c *MockClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// After returns a channel receiving the clock's time once it has been advanced by d
+func (c *MockClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, mockWaiter{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Sleep blocks until the clock has been advanced by d
+func (c *MockClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// Advance moves the clock forward by d, firing the channels of After and waking Sleep calls that are due
+func (c *MockClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			waiters = append(waiters, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = waiters
+}
+
+// remove stops waiting to send on ch
+func (c *MockClock) remove(ch <-chan time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, w := range c.waiters {
+		if w.ch == ch {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// Waiters returns how many After channels and Sleep calls are waiting for the clock to advance
+func (c *MockClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.waiters)
+}
+
+// newTimer returns a channel receiving the time once d has elapsed on c, and a function that stops waiting for it
+func newTimer(c Clock, d time.Duration) (<-chan time.Time, func()) {
+	switch c := c.(type) {
+	case RealClock:
+		// Timers from time.After aren't released until they fire, so use one that can be stopped
+		t := time.NewTimer(d)
+		return t.C, func() { t.Stop() }
+	case *MockClock:
+		ch := c.After(d)
+		return ch, func() { c.remove(ch) }
+	}
+	return c.After(d), func() {}
+}
@@ -0,0 +1,94 @@
+package sse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockClock(t *testing.T) {
+	assert := assert.New(t)
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewMockClock(start)
+	assert.Equal(start, c.Now())
+
+	after := c.After(time.Second)
+	assert.Equal(start, <-c.After(0))
+	slept := make(chan struct{})
+	go func() {
+		c.Sleep(2 * time.Second)
+		close(slept)
+	}()
+	assert.Eventually(func() bool { return c.Waiters() == 2 }, time.Second, time.Millisecond)
+
+	c.Advance(time.Second)
+	assert.Equal(start.Add(time.Second), <-after)
+	assert.Equal(1, c.Waiters())
+	select {
+	case <-slept:
+		t.Fatal("slept before the clock advanced enough")
+	default:
+	}
+
+	c.Advance(time.Second)
+	<-slept
+	assert.Equal(start.Add(2*time.Second), c.Now())
+	assert.Equal(0, c.Waiters())
+}
+
+func TestWithClock(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var connections atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if connections.Add(1) > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("data: a\n\n"))
+	}))
+	defer server.Close()
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewMockClock(start)
+	s, err := New(server.URL, WithClock(c), WithMaxReconnectAttempts(1))
+	require.NoError(err)
+
+	assert.Equal("a", (<-s.Events()).Data)
+	assert.True(start.Equal(s.Stats().LastEventTime))
+
+	reconnect := <-s.Reconnects()
+	assert.Equal(3*time.Second, reconnect.Delay)
+	assert.Equal(start, reconnect.At)
+	assert.Eventually(func() bool { return c.Waiters() == 1 }, time.Second, time.Millisecond)
+	assert.Equal(Connecting, s.State())
+
+	c.Advance(3 * time.Second)
+	for range s.Events() {
+	}
+	assert.Equal(int32(2), connections.Load())
+}
+
+func TestNewAccumulatorWithClock(t *testing.T) {
+	c := NewMockClock(time.Now())
+	s := newStream("", WithClock(c))
+	s.events = make(chan Event)
+	batches := NewAccumulator(s, time.Second, 10)
+
+	s.events <- Event{Data: "a"}
+	s.events <- Event{Data: "b"}
+	assert.Eventually(t, func() bool { return c.Waiters() == 1 }, time.Second, time.Millisecond)
+	c.Advance(time.Second)
+	assert.Equal(t, []Event{{Data: "a"}, {Data: "b"}}, <-batches)
+
+	close(s.events)
+	_, ok := <-batches
+	assert.False(t, ok)
+}
@@ -0,0 +1,264 @@
+package sse
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// First consumes s until an event for which fn returns true is received and returns it.
+//
+// false is returned if the stream closes before a matching event is received.
+func First(ctx context.Context, s Stream, fn func(Event) bool) (Event, bool, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return Event{}, false, ctx.Err()
+		case event, ok := <-s.Events():
+			if !ok {
+				return Event{}, false, nil
+			}
+			if fn(event) {
+				return event, true, nil
+			}
+		}
+	}
+}
+
+// Each calls fn for each event of s until s closes.
+//
+// The first error fn returns is returned, as is the context's error if ctx is done first.
+func Each(ctx context.Context, s Stream, fn func(Event) error) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-s.Events():
+			if !ok {
+				return nil
+			}
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// RetryOnError calls fn for each event of s, retrying it up to maxAttempts times with delay between attempts,
+// and sends the results on the first returned channel.
+//
+// The last error for an event that fails every attempt is sent on the second returned channel, and the next event is processed.
+// Both channels must be consumed, and are closed when s closes or ctx is done.
+func RetryOnError[T any](ctx context.Context, s Stream, fn func(Event) (T, error), maxAttempts int, delay time.Duration) (<-chan T, <-chan error) {
+	results, errs := make(chan T), make(chan error)
+	go func() {
+		defer close(results)
+		defer close(errs)
+
+		for {
+			var event Event
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-s.Events():
+				if !ok {
+					return
+				}
+				event = e
+			}
+
+			result, err := fn(event)
+			for attempt := 1; err != nil && attempt < maxAttempts; attempt++ {
+				wait, stop := newTimer(s.clock, delay)
+				select {
+				case <-ctx.Done():
+					stop()
+					return
+				case <-wait:
+				}
+				result, err = fn(event)
+			}
+
+			if err != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case errs <- errors.Wrap(err, "processing event"):
+				}
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case results <- result:
+			}
+		}
+	}()
+	return results, errs
+}
+
+// WithEventContext returns a stream whose events carry the context fn returns for them in Event.Ctx
+//
+// The returned stream consumes the events of s.
+func (s Stream) WithEventContext(fn func(Event) context.Context) Stream {
+	return s.wrap(func(event Event, events chan<- Event) {
+		event.Ctx = fn(event)
+		events <- event
+	})
+}
+
+// Filter returns a channel of the events of s for which pred returns true
+//
+// The channel consumes the events of s, and is closed when s closes.
+func (s Stream) Filter(pred func(Event) bool) <-chan Event {
+	return s.wrap(func(event Event, events chan<- Event) {
+		if pred(event) {
+			events <- event
+		}
+	}).Events()
+}
+
+// Map returns a channel of the events fn maps each event of s to
+//
+// The channel consumes the events of s, and is closed when s closes.
+func (s Stream) Map(fn func(Event) Event) <-chan Event {
+	return s.wrap(func(event Event, events chan<- Event) {
+		events <- fn(event)
+	}).Events()
+}
+
+// Flatten returns a stream of the events fn maps each event of s to
+//
+// The returned stream consumes the events of s.
+func Flatten(s Stream, fn func(Event) []Event) Stream {
+	return s.wrap(func(event Event, events chan<- Event) {
+		for _, e := range fn(event) {
+			events <- e
+		}
+	})
+}
+
+// Sample returns a stream of every every-th event of s, starting with the every-th.
+//
+// Every event is forwarded if every is less than 2. The returned stream consumes the events of s.
+func Sample(s Stream, every int) Stream {
+	n := 0
+	return s.wrap(func(event Event, events chan<- Event) {
+		n++
+		if n >= every {
+			n = 0
+			events <- event
+		}
+	})
+}
+
+// lowPriorityBufferSize is how many low priority events PriorityFanOut buffers before dropping them
+const lowPriorityBufferSize = 64
+
+// PriorityFanOut splits the events of s into events for which high returns true and the rest.
+//
+// High priority events are never dropped, s is blocked until they're received. Low priority events are buffered,
+// and dropped while the buffer is full. Both channels are closed when s closes.
+func PriorityFanOut(s Stream, high func(Event) bool) (highCh, lowCh <-chan Event) {
+	highEvents, lowEvents := make(chan Event), make(chan Event, lowPriorityBufferSize)
+	go func() {
+		defer close(highEvents)
+		defer close(lowEvents)
+
+		for event := range s.Events() {
+			if high(event) {
+				highEvents <- event
+				continue
+			}
+			select {
+			case lowEvents <- event:
+			default:
+			}
+		}
+	}()
+	return highEvents, lowEvents
+}
+
+// NewAccumulator batches the events of s.
+//
+// A batch is sent once it has maxBatch events or window has elapsed since its first event.
+// Any partial batch is sent when s closes, then the returned channel is closed.
+func NewAccumulator(s Stream, window time.Duration, maxBatch int) <-chan []Event {
+	batches := make(chan []Event)
+	go func() {
+		defer close(batches)
+
+		var (
+			batch []Event
+			flush <-chan time.Time
+			stop  = func() {}
+		)
+		send := func() {
+			stop()
+			if len(batch) != 0 {
+				batches <- batch
+			}
+			batch, flush, stop = nil, nil, func() {}
+		}
+
+		for {
+			select {
+			case event, ok := <-s.Events():
+				if !ok {
+					send()
+					return
+				}
+				batch = append(batch, event)
+				if len(batch) == 1 {
+					flush, stop = newTimer(s.clock, window)
+				}
+				if len(batch) >= maxBatch {
+					send()
+				}
+			case <-flush:
+				send()
+			}
+		}
+	}()
+	return batches
+}
+
+// wrap returns a copy of s whose events are sent by fn for each event of s
+func (s Stream) wrap(fn func(event Event, events chan<- Event)) Stream {
+	source, events := s.events, make(chan Event)
+	go func() {
+		defer close(events)
+		for event := range source {
+			fn(event, events)
+		}
+	}()
+
+	s.events = events
+	return s
+}
+
+// GroupBy consumes s until it closes and partitions its events by key.
+//
+// Each channel in the returned map holds the events for one key, in the order they were received, and is closed.
+// GroupBy blocks until s closes and buffers every event of s in memory until then, without a bound, so it's only
+// suitable for finite streams such as one closed after WithMaxReconnectAttempts. Use Each to partition the events
+// of a long-lived stream as they arrive.
+func GroupBy[K comparable](s Stream, key func(Event) K) map[K]<-chan Event {
+	groups := make(map[K][]Event)
+	for event := range s.Events() {
+		k := key(event)
+		groups[k] = append(groups[k], event)
+	}
+
+	channels := make(map[K]<-chan Event, len(groups))
+	for k, events := range groups {
+		ch := make(chan Event, len(events))
+		for _, event := range events {
+			ch <- event
+		}
+		close(ch)
+		channels[k] = ch
+	}
+	return channels
+}
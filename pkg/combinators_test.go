@@ -0,0 +1,225 @@
+package sse
+
+import (
+	"context"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStream parses input in the background
+func newTestStream(input string) Stream {
+	s := newStream("", WithMaxReconnectAttempts(0))
+	go s.parse(ioutil.NopCloser(strings.NewReader(input)))
+	return s
+}
+
+func TestFirst(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	isScore := func(event Event) bool { return event.Type == "score" }
+
+	s := newTestStream("data: foo\n\nevent: score\ndata: 1\n\nevent: score\ndata: 2\n\n")
+	event, ok, err := First(context.Background(), s, isScore)
+	require.NoError(err)
+	assert.True(ok)
+	assert.Equal(Event{Type: "score", Data: "1"}, event)
+
+	s = newTestStream("data: foo\n\n")
+	_, ok, err = First(context.Background(), s, isScore)
+	require.NoError(err)
+	assert.False(ok)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, ok, err = First(ctx, newStream(""), isScore)
+	assert.Equal(context.Canceled, err)
+	assert.False(ok)
+}
+
+func TestEach(t *testing.T) {
+	assert := assert.New(t)
+
+	var data []string
+	collect := func(event Event) error {
+		if event.Data == "stop" {
+			return errors.New("stopped")
+		}
+		data = append(data, event.Data)
+		return nil
+	}
+
+	err := Each(context.Background(), newTestStream("data: 1\n\ndata: 2\n\n"), collect)
+	assert.NoError(err)
+	assert.Equal([]string{"1", "2"}, data)
+
+	data = nil
+	err = Each(context.Background(), newTestStream("data: 1\n\ndata: stop\n\ndata: 2\n\n"), collect)
+	assert.EqualError(err, "stopped")
+	assert.Equal([]string{"1"}, data)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(context.Canceled, Each(ctx, newStream(""), collect))
+}
+
+func TestRetryOnError(t *testing.T) {
+	assert := assert.New(t)
+
+	attempts := make(map[string]int)
+	results, errs := RetryOnError(context.Background(), newTestStream("data: 1\n\ndata: 2\n\ndata: 3\n\n"), func(event Event) (int, error) {
+		attempts[event.Data]++
+		switch {
+		case event.Data == "2" && attempts["2"] < 3:
+			return 0, errors.New("transient")
+		case event.Data == "3":
+			return 0, errors.New("permanent")
+		}
+		return len(attempts), nil
+	}, 3, time.Millisecond)
+
+	assert.Equal(1, <-results)
+	assert.Equal(2, <-results)
+	assert.EqualError(<-errs, "processing event: permanent")
+	_, ok := <-results
+	assert.False(ok)
+	assert.Equal(map[string]int{"1": 1, "2": 3, "3": 3}, attempts)
+}
+
+func TestGroupBy(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newTestStream("event: a\ndata: 1\n\nevent: b\ndata: 2\n\nevent: a\ndata: 3\n\n")
+	groups := GroupBy(s, func(event Event) string { return event.Type })
+
+	assert.Len(groups, 2)
+	var a []string
+	for event := range groups["a"] {
+		a = append(a, event.Data)
+	}
+	assert.Equal([]string{"1", "3"}, a)
+	assert.Equal(Event{Type: "b", Data: "2"}, <-groups["b"])
+}
+
+func TestWithEventContext(t *testing.T) {
+	type traceIDKey struct{}
+
+	s := newTestStream("id: 1\ndata: foo\n\nid: 2\ndata: bar\n\n").WithEventContext(func(event Event) context.Context {
+		return context.WithValue(context.Background(), traceIDKey{}, "trace-"+event.Data)
+	})
+
+	var traceIDs []any
+	for event := range s.Events() {
+		traceIDs = append(traceIDs, event.Ctx.Value(traceIDKey{}))
+	}
+	assert.Equal(t, []any{"trace-foo", "trace-bar"}, traceIDs)
+}
+
+func TestStreamFilter(t *testing.T) {
+	var data []string
+	for event := range newTestStream("event: score\ndata: 1\n\ndata: foo\n\nevent: score\ndata: 2\n\n").Filter(func(event Event) bool {
+		return event.Type == "score"
+	}) {
+		data = append(data, event.Data)
+	}
+	assert.Equal(t, []string{"1", "2"}, data)
+}
+
+func TestStreamMap(t *testing.T) {
+	var data []string
+	for event := range newTestStream("data: a\n\ndata: b\n\n").Map(func(event Event) Event {
+		event.Data = strings.ToUpper(event.Data)
+		return event
+	}) {
+		data = append(data, event.Data)
+	}
+	assert.Equal(t, []string{"A", "B"}, data)
+}
+
+func TestFlatten(t *testing.T) {
+	s := Flatten(newTestStream("event: batch\ndata: a,b\n\nevent: batch\ndata: \n\nevent: batch\ndata: c\n\n"), func(event Event) []Event {
+		var events []Event
+		for _, data := range strings.Split(event.Data, ",") {
+			if data != "" {
+				events = append(events, Event{Type: "item", Data: data})
+			}
+		}
+		return events
+	})
+
+	var items []string
+	for event := range s.Events() {
+		items = append(items, event.Data)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, items)
+}
+
+func TestSample(t *testing.T) {
+	input := "data: 1\n\ndata: 2\n\ndata: 3\n\ndata: 4\n\ndata: 5\n\n"
+
+	sampled := func(every int) []string {
+		var data []string
+		for event := range Sample(newTestStream(input), every).Events() {
+			data = append(data, event.Data)
+		}
+		return data
+	}
+	assert.Equal(t, []string{"2", "4"}, sampled(2))
+	assert.Equal(t, []string{"3"}, sampled(3))
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, sampled(1))
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, sampled(0))
+}
+
+func TestPriorityFanOut(t *testing.T) {
+	assert := assert.New(t)
+
+	var input strings.Builder
+	for i := 0; i < lowPriorityBufferSize+1; i++ {
+		input.WriteString("event: metric\ndata: " + strconv.Itoa(i) + "\n\n")
+	}
+	input.WriteString("event: alert\ndata: fire\n\n")
+
+	highCh, lowCh := PriorityFanOut(newTestStream(input.String()), func(event Event) bool {
+		return event.Type == "alert"
+	})
+
+	assert.Equal(Event{Type: "alert", Data: "fire"}, <-highCh)
+	_, ok := <-highCh
+	assert.False(ok)
+
+	var metrics []Event
+	for event := range lowCh {
+		metrics = append(metrics, event)
+	}
+	assert.Len(metrics, lowPriorityBufferSize)
+	assert.Equal("0", metrics[0].Data)
+}
+
+func TestNewAccumulator(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newTestStream("data: 1\n\ndata: 2\n\ndata: 3\n\n")
+	var batches [][]Event
+	for batch := range NewAccumulator(s, time.Hour, 2) {
+		batches = append(batches, batch)
+	}
+	assert.Equal([][]Event{
+		{{Type: "message", Data: "1"}, {Type: "message", Data: "2"}},
+		{{Type: "message", Data: "3"}},
+	}, batches)
+
+	s = newStream("")
+	batchCh := NewAccumulator(s, 10*time.Millisecond, 10)
+	s.events <- Event{Type: "message", Data: "1"}
+	assert.Equal([]Event{{Type: "message", Data: "1"}}, <-batchCh)
+	close(s.events)
+	_, ok := <-batchCh
+	assert.False(ok)
+}
@@ -0,0 +1,70 @@
+package sse
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// WithCompression requests the stream gzip or deflate compressed with an Accept-Encoding header and decompresses
+// responses according to their Content-Encoding header.
+//
+// http.Transport only decompresses gzip on its own when the request doesn't set Accept-Encoding, which clients
+// with DisableCompression set don't.
+func WithCompression(enabled bool) Option {
+	return func(s *Stream) {
+		s.compression = enabled
+	}
+}
+
+// decompress returns body decompressed according to the Content-Encoding of resp
+func decompress(resp *http.Response, body io.ReadCloser) io.ReadCloser {
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		return &decompressingReader{body: body, newReader: func(r *bufio.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		}}
+	case "deflate":
+		return &decompressingReader{body: body, newReader: newDeflateReader}
+	default:
+		return body
+	}
+}
+
+// newDeflateReader reads deflate content from r, which should be wrapped in the zlib format but often isn't
+func newDeflateReader(r *bufio.Reader) (io.Reader, error) {
+	header, err := r.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(r)
+	}
+	return flate.NewReader(r), nil
+}
+
+// decompressingReader decompresses body, starting on the first read since decompressors read their header eagerly
+type decompressingReader struct {
+	body      io.ReadCloser
+	newReader func(*bufio.Reader) (io.Reader, error)
+	r         io.Reader
+	err       error
+}
+
+func (d *decompressingReader) Read(p []byte) (int, error) {
+	if d.r == nil && d.err == nil {
+		d.r, d.err = d.newReader(bufio.NewReader(d.body))
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.r.Read(p)
+}
+
+func (d *decompressingReader) Close() error {
+	return d.body.Close()
+}
@@ -0,0 +1,59 @@
+package sse
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCompression(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		encoding  string
+		newWriter func(io.Writer) io.WriteCloser
+	}{
+		{"gzip", "gzip", func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }},
+		{"zlib deflate", "deflate", func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }},
+		{"raw deflate", "deflate", func(w io.Writer) io.WriteCloser {
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		}},
+		{"identity", "", func(w io.Writer) io.WriteCloser { return nopWriteCloser{w} }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			acceptEncoding := make(chan string, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				acceptEncoding <- r.Header.Get("Accept-Encoding")
+				if tc.encoding != "" {
+					w.Header().Set("Content-Encoding", tc.encoding)
+				}
+				cw := tc.newWriter(w)
+				cw.Write([]byte("data: a\n\ndata: b\n\n"))
+				cw.Close()
+			}))
+			defer server.Close()
+
+			s, err := New(server.URL, WithCompression(true), WithMaxReconnectAttempts(0))
+			require.NoError(t, err)
+			var data []string
+			for event := range s.Events() {
+				data = append(data, event.Data)
+			}
+			assert.Equal(t, []string{"a", "b"}, data)
+			assert.Equal(t, "gzip, deflate", <-acceptEncoding)
+		})
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
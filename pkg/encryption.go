@@ -0,0 +1,90 @@
+package sse
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+
+	"github.com/pkg/errors"
+)
+
+// WithEncryption encrypts the data of every event with AES-GCM before it is dispatched.
+//
+// key must be 16, 24, or 32 bytes to select AES-128, AES-192, or AES-256.
+// The data of each event is replaced with the base64 encoding of a random nonce followed by the ciphertext.
+// Use DecryptData to recover the original data.
+func WithEncryption(key []byte) Option {
+	return func(s *Stream) {
+		aead, err := newAEAD(key)
+		if err != nil {
+			s.optionErr = err
+			return
+		}
+		s.aead = aead
+	}
+}
+
+// DecryptData decrypts the data of an event dispatched by a stream using WithEncryption
+func DecryptData(key []byte, data string) (string, error) {
+	d, err := NewDecrypter(key)
+	if err != nil {
+		return "", err
+	}
+	return d.Decrypt(data)
+}
+
+// Decrypter decrypts the data of events dispatched by a stream using WithEncryption with a key.
+//
+// It's safe for concurrent use, and avoids setting up the cipher for each event like DecryptData does.
+type Decrypter struct {
+	aead cipher.AEAD
+}
+
+// NewDecrypter returns a Decrypter for key, which must be 16, 24, or 32 bytes
+func NewDecrypter(key []byte) (*Decrypter, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Decrypter{aead: aead}, nil
+}
+
+// Decrypt decrypts the data of an event
+func (d *Decrypter) Decrypt(data string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", errors.Wrap(err, "decoding data")
+	}
+	if len(sealed) < d.aead.NonceSize() {
+		return "", errors.New("data too short")
+	}
+
+	nonce, ciphertext := sealed[:d.aead.NonceSize()], sealed[d.aead.NonceSize():]
+	plaintext, err := d.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "decrypting data")
+	}
+
+	return string(plaintext), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating cipher")
+	}
+
+	aead, err := cipher.NewGCM(block)
+	return aead, errors.Wrap(err, "creating gcm")
+}
+
+func encryptData(aead cipher.AEAD, data string) (string, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.Wrap(err, "generating nonce")
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(data), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
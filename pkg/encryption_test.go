@@ -0,0 +1,41 @@
+package sse
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithEncryption(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	s := newStream("", WithEncryption(key), WithMaxReconnectAttempts(0))
+	s.events = make(chan Event, 2)
+	require.NoError(s.optionErr)
+
+	require.NoError(s.parse(ioutil.NopCloser(strings.NewReader("event: secret\ndata: foo\n\nevent: secret\ndata: foo\n\n"))))
+
+	first, second := <-s.events, <-s.events
+	assert.Equal("secret", first.Type)
+	assert.NotEqual("foo", first.Data)
+	assert.NotEqual(first.Data, second.Data, "nonce should be random")
+
+	for _, event := range []Event{first, second} {
+		data, err := DecryptData(key, event.Data)
+		require.NoError(err)
+		assert.Equal("foo", data)
+	}
+
+	_, err := DecryptData([]byte("fedcba9876543210fedcba9876543210"), first.Data)
+	assert.Error(err)
+}
+
+func TestWithEncryptionInvalidKey(t *testing.T) {
+	_, err := New("http://localhost", WithEncryption([]byte("short")))
+	assert.Error(t, err)
+}
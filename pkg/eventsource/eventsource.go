@@ -0,0 +1,26 @@
+// Package eventsource implements event sourcing over Server-Sent Event streams
+package eventsource
+
+import (
+	"context"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+)
+
+// Rebuild folds the events of stream into state, starting from initial, until the stream closes
+//
+// If ctx is done first the state so far is returned with ctx.Err().
+func Rebuild[S any](ctx context.Context, stream sse.Stream, initial S, apply func(S, sse.Event) S) (S, error) {
+	state := initial
+	for {
+		select {
+		case <-ctx.Done():
+			return state, ctx.Err()
+		case event, ok := <-stream.Events():
+			if !ok {
+				return state, nil
+			}
+			state = apply(state, event)
+		}
+	}
+}
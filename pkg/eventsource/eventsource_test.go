@@ -0,0 +1,51 @@
+package eventsource
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebuild(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("event: deposit\ndata: 10\n\nevent: withdraw\ndata: 3\n\nevent: deposit\ndata: 5\n\n"))
+	}))
+	defer server.Close()
+
+	stream, err := sse.New(server.URL, sse.WithMaxReconnectAttempts(0))
+	require.NoError(t, err)
+
+	balance, err := Rebuild(context.Background(), stream, 100, func(balance int, event sse.Event) int {
+		amount, _ := strconv.Atoi(event.Data)
+		if event.Type == "withdraw" {
+			return balance - amount
+		}
+		return balance + amount
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 112, balance)
+}
+
+func TestRebuildCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	stream, err := sse.New(server.URL, sse.WithMaxReconnectAttempts(0))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	state, err := Rebuild(ctx, stream, "initial", func(string, sse.Event) string { return "applied" })
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, "initial", state)
+}
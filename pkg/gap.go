@@ -0,0 +1,62 @@
+package sse
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// ErrGapDetected is matched by the GapError WithGapDetection reports when events are missed across a reconnect
+var ErrGapDetected = errors.New("gap detected")
+
+// GapError reports the IDs of the events missed across a reconnect
+type GapError struct {
+	// From and To are the first and last missed IDs
+	From, To int64
+}
+
+func (e *GapError) Error() string {
+	return fmt.Sprintf("gap detected: missed events %d to %d", e.From, e.To)
+}
+
+// Is makes GapError match ErrGapDetected
+func (e *GapError) Is(target error) bool {
+	return target == ErrGapDetected
+}
+
+// WithGapDetection checks that no events are missed across reconnects when events have numeric IDs.
+//
+// The ID of the first event after a reconnect is compared with the last ID seen before it.
+// If any IDs between them are missing a GapError is sent to the channel returned by Stream.Errors,
+// so a compensating query can fetch the missed events.
+func WithGapDetection() Option {
+	return func(s *Stream) {
+		s.gaps = new(gapDetector)
+	}
+}
+
+// gapDetector tracks the last numeric event ID, it's only used by the goroutine parsing the stream
+type gapDetector struct {
+	lastSeen    int64
+	seen        bool
+	reconnected bool
+}
+
+// check returns the gap between the last ID seen and id if it's the first event since a reconnect
+func (g *gapDetector) check(id string) *GapError {
+	reconnected := g.reconnected
+	g.reconnected = false
+
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	var gap *GapError
+	if reconnected && g.seen && n > g.lastSeen+1 {
+		gap = &GapError{From: g.lastSeen + 1, To: n - 1}
+	}
+	g.lastSeen, g.seen = n, true
+	return gap
+}
@@ -0,0 +1,60 @@
+package sse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGapDetector(t *testing.T) {
+	g := new(gapDetector)
+	assert.Nil(t, g.check("1"))
+	assert.Nil(t, g.check("5"))
+
+	g.reconnected = true
+	assert.Nil(t, g.check("6"))
+
+	g.reconnected = true
+	assert.Equal(t, &GapError{From: 7, To: 9}, g.check("10"))
+	assert.Nil(t, g.check("20"))
+
+	g.reconnected = true
+	assert.Nil(t, g.check("not a number"))
+	assert.Nil(t, g.check("30"))
+}
+
+func TestWithGapDetection(t *testing.T) {
+	var connections int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connections++
+		switch connections {
+		case 1:
+			w.Write([]byte("retry: 1\nid: 1\ndata: a\n\nid: 2\ndata: b\n\n"))
+		case 2:
+			w.Write([]byte("id: 5\ndata: e\n\n"))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	s, err := New(server.URL, WithGapDetection(), WithMaxReconnectAttempts(1), WithReconnectionTime(time.Millisecond))
+	require.NoError(t, err)
+	for range s.Events() {
+	}
+
+	var gaps []*GapError
+	for err := range s.Errors() {
+		var gap *GapError
+		if errors.As(err, &gap) {
+			assert.ErrorIs(t, err, ErrGapDetected)
+			gaps = append(gaps, gap)
+		}
+	}
+	assert.Equal(t, []*GapError{{From: 3, To: 4}}, gaps)
+}
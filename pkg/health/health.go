@@ -0,0 +1,118 @@
+// Package health checks that a Server-Sent Event stream is alive
+package health
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+)
+
+const (
+	defaultMaxSilence    = time.Minute
+	defaultMaxReconnects = 5
+)
+
+// Status is the health of a stream
+type Status int
+
+const (
+	// Healthy means the stream is open and receiving events
+	Healthy Status = iota
+	// Degraded means the stream is connecting, hasn't received an event recently, or has reconnected too often
+	Degraded
+	// Unhealthy means the stream is closed
+	Unhealthy
+)
+
+func (s Status) String() string {
+	switch s {
+	case Healthy:
+		return "healthy"
+	case Degraded:
+		return "degraded"
+	case Unhealthy:
+		return "unhealthy"
+	}
+	return "unknown"
+}
+
+// Checker checks the health of a stream
+type Checker struct {
+	stream        sse.Stream
+	maxSilence    time.Duration
+	maxReconnects uint64
+}
+
+// CheckerOption configures a Checker
+type CheckerOption func(*Checker)
+
+// WithMaxSilence sets how long the stream can go without an event before it's degraded.
+// The default is one minute.
+func WithMaxSilence(d time.Duration) CheckerOption {
+	return func(c *Checker) {
+		c.maxSilence = d
+	}
+}
+
+// WithMaxReconnects sets how many times the stream can reconnect before it's degraded.
+// The default is 5.
+func WithMaxReconnects(n uint64) CheckerOption {
+	return func(c *Checker) {
+		c.maxReconnects = n
+	}
+}
+
+// NewChecker constructs a Checker for s
+func NewChecker(s sse.Stream, opts ...CheckerOption) *Checker {
+	c := &Checker{
+		stream:        s,
+		maxSilence:    defaultMaxSilence,
+		maxReconnects: defaultMaxReconnects,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Check returns the current health of the stream
+func (c *Checker) Check(ctx context.Context) Status {
+	switch c.stream.State() {
+	case sse.Closed:
+		return Unhealthy
+	case sse.Connecting:
+		return Degraded
+	}
+
+	stats := c.stream.Stats()
+	if stats.ConnectCount > c.maxReconnects+1 {
+		return Degraded
+	}
+
+	silence := stats.Uptime
+	if !stats.LastEventTime.IsZero() {
+		silence = time.Since(stats.LastEventTime)
+	}
+	if silence > c.maxSilence {
+		return Degraded
+	}
+
+	return Healthy
+}
+
+// Handler returns an http.Handler that responds with the status of the stream.
+// The response code is 503 if the stream is unhealthy and 200 otherwise.
+func (c *Checker) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := c.Check(r.Context())
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if status == Unhealthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Write([]byte(status.String()))
+	})
+}
@@ -0,0 +1,51 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	events := make(chan string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		for data := range events {
+			w.Write([]byte("data: " + data + "\n\n"))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	s, err := sse.New(server.URL, sse.WithMaxReconnectAttempts(0))
+	require.NoError(err)
+
+	checker := NewChecker(s, WithMaxSilence(50*time.Millisecond))
+	assert.Equal(Healthy, checker.Check(context.Background()))
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(Degraded, checker.Check(context.Background()))
+
+	events <- "foo"
+	<-s.Events()
+	assert.Equal(Healthy, checker.Check(context.Background()))
+
+	close(events)
+	for range s.Events() {
+	}
+	assert.Equal(Unhealthy, checker.Check(context.Background()))
+
+	recorder := httptest.NewRecorder()
+	checker.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal("unhealthy", recorder.Body.String())
+}
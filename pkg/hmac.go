@@ -0,0 +1,49 @@
+package sse
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var hmacAlgorithms = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// WithHMACAuth signs every request with an HMAC of the request method, URL, and the current unix time, separated by newlines.
+//
+// The hex encoded signature is sent in the X-HMAC-Signature header and the time in the X-HMAC-Timestamp header.
+// algorithm is one of sha1, sha256, or sha512.
+func WithHMACAuth(secret string, algorithm string) Option {
+	return func(s *Stream) {
+		newHash, ok := hmacAlgorithms[strings.ToLower(algorithm)]
+		if !ok {
+			s.optionErr = errors.Errorf("unsupported hmac algorithm %q", algorithm)
+			return
+		}
+		s.hmacSecret = []byte(secret)
+		s.hmacHash = newHash
+	}
+}
+
+func (s Stream) signRequest(req *http.Request) {
+	timestamp := strconv.FormatInt(s.clock.Now().Unix(), 10)
+	req.Header.Set("X-HMAC-Timestamp", timestamp)
+	req.Header.Set("X-HMAC-Signature", signature(s.hmacHash, s.hmacSecret, req.Method, req.URL.String(), timestamp))
+}
+
+func signature(newHash func() hash.Hash, secret []byte, method, url, timestamp string) string {
+	mac := hmac.New(newHash, secret)
+	mac.Write([]byte(method + "\n" + url + "\n" + timestamp))
+	return hex.EncodeToString(mac.Sum(nil))
+}
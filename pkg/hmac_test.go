@@ -0,0 +1,35 @@
+package sse
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithHMACAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timestamp := r.Header.Get("X-HMAC-Timestamp")
+		assert.NotEmpty(t, timestamp)
+
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write([]byte("GET\nhttp://" + r.Host + "/events?a=b\n" + timestamp))
+		assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), r.Header.Get("X-HMAC-Signature"))
+	}))
+	defer server.Close()
+
+	s, err := New(server.URL+"/events?a=b", WithHMACAuth("secret", "SHA256"), WithMaxReconnectAttempts(0))
+	require.NoError(t, err)
+	for range s.Events() {
+	}
+}
+
+func TestWithHMACAuthUnsupportedAlgorithm(t *testing.T) {
+	_, err := New("http://localhost", WithHMACAuth("secret", "md5"))
+	assert.Error(t, err)
+}
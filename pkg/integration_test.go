@@ -0,0 +1,122 @@
+package sse
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegrationStream(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	requests := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, chunk := range []string{
+			": connected\n\n",
+			"id: 1\nevent: score\ndata: 1\n\n",
+			"data: multi\r\ndata: line\r\n\r\n",
+			"id: 2\ndata: split ",
+			"across writes\n\n",
+		} {
+			w.Write([]byte(chunk))
+			flusher.Flush()
+		}
+	}))
+	defer server.Close()
+
+	s, err := New(server.URL, WithMaxReconnectAttempts(0), WithHeader("Authorization", "Bearer token"))
+	require.NoError(err)
+
+	r := <-requests
+	assert.Equal(http.MethodGet, r.Method)
+	assert.Equal("text/event-stream", r.Header.Get("Accept"))
+	assert.Empty(r.Header.Get("Content-Type"))
+	assert.Equal("no-cache", r.Header.Get("Cache-Control"))
+	assert.Equal("Bearer token", r.Header.Get("Authorization"))
+	assert.Empty(r.Header.Get("Last-Event-ID"))
+
+	var events []Event
+	for event := range s.Events() {
+		events = append(events, event)
+	}
+	assert.Equal([]Event{
+		{Type: "score", Data: "1", ID: "1"},
+		{Type: "message", Data: "multi\nline", ID: "1"},
+		{Type: "message", Data: "split across writes", ID: "2"},
+	}, events)
+	assert.Equal(Closed, s.State())
+}
+
+func TestIntegrationReconnect(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	lastEventIDs := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastEventIDs <- r.Header.Get("Last-Event-ID")
+		if r.Header.Get("Last-Event-ID") == "" {
+			w.Write([]byte("retry: 1\nid: 1\ndata: first\n\n"))
+			return
+		}
+		w.Write([]byte("id: 2\ndata: second\n\n"))
+	}))
+	defer server.Close()
+
+	s, err := New(server.URL, WithMaxReconnectAttempts(1))
+	require.NoError(err)
+
+	assert.Equal(Event{Type: "message", Data: "first", ID: "1"}, <-s.Events())
+	assert.Equal(Event{Type: "message", Data: "second", ID: "2"}, <-s.Events())
+	assert.Equal("", <-lastEventIDs)
+	assert.Equal("1", <-lastEventIDs)
+	require.NoError(s.Close())
+}
+
+func TestIntegrationErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	s, err := New(server.URL)
+	assert.EqualError(t, err, "unexpected status code 503")
+	assert.Equal(t, Closed, s.State())
+	_, ok := <-s.Events()
+	assert.False(t, ok)
+}
+
+func TestIntegrationErrorStatusReusesConnection(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Write([]byte("retry: 1\ndata: a\n\n"))
+			return
+		}
+		http.Error(w, strings.Repeat("unavailable ", 100), http.StatusServiceUnavailable)
+	}))
+	var connections atomic.Int32
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	s, err := New(server.URL, WithMaxReconnectAttempts(3))
+	require.NoError(t, err)
+	for range s.Events() {
+	}
+	assert.Equal(t, int32(4), requests.Load())
+	assert.Equal(t, int32(1), connections.Load(), "error responses are drained and closed so their connection is reused")
+}
@@ -0,0 +1,126 @@
+// Package journal records dispatched Server-Sent Events in an append-only file so a stream can be resumed after a crash.
+//
+// Events are written in the event stream format https://www.w3.org/TR/2015/REC-eventsource-20150203/#parsing-an-event-stream
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/pkg/errors"
+)
+
+// Journal is an append-only log of dispatched events.
+// It implements sse.LastEventIDStore.
+type Journal struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewJournal opens or creates the journal at path
+func NewJournal(path string) (*Journal, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening journal")
+	}
+
+	return &Journal{path: path, file: file}, nil
+}
+
+// Store appends an event to the journal
+func (j *Journal) Store(event sse.Event, lastEventID string) error {
+	var buf bytes.Buffer
+	if lastEventID != "" {
+		buf.WriteString("id: " + lastEventID + "\n")
+	}
+	buf.WriteString("event: " + event.Type + "\n")
+	for _, line := range strings.Split(event.Data, "\n") {
+		buf.WriteString("data: " + line + "\n")
+	}
+	buf.WriteByte('\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.file.Write(buf.Bytes()); err != nil {
+		return errors.Wrap(err, "writing journal")
+	}
+	return errors.Wrap(j.file.Sync(), "syncing journal")
+}
+
+// LastEventID returns the last event ID recorded in the journal
+func (j *Journal) LastEventID() (string, error) {
+	_, id, err := j.Recover()
+	return id, err
+}
+
+// Recover replays the journal, returning every complete event and the last event ID.
+//
+// A trailing event that was only partially written is ignored.
+func (j *Journal) Recover() ([]sse.Event, string, error) {
+	file, err := os.Open(j.path)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "opening journal")
+	}
+	defer file.Close()
+
+	events, lastEventID, err := replay(file)
+	return events, lastEventID, errors.Wrap(err, "reading journal")
+}
+
+// Close closes the journal file
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.file.Close()
+}
+
+func replay(r io.Reader) ([]sse.Event, string, error) {
+	var (
+		events      []sse.Event
+		lastEventID string
+		id          string
+		event       sse.Event
+		data        []string
+	)
+
+	// Lines are read without a length limit since Store writes data lines whole
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			// A trailing line without a newline was only partially written
+			return events, lastEventID, nil
+		} else if err != nil {
+			return events, lastEventID, err
+		}
+		line = strings.TrimSuffix(line, "\n")
+
+		if line == "" {
+			event.ID = id
+			event.Data = strings.Join(data, "\n")
+			events = append(events, event)
+			lastEventID = id
+			event, data = sse.Event{}, nil
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ": ")
+		switch field {
+		case "id":
+			id = value
+		case "event":
+			event.Type = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+}
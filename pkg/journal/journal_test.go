@@ -0,0 +1,82 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournal(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "journal")
+	j, err := NewJournal(path)
+	require.NoError(err)
+
+	events := []sse.Event{
+		{Type: "message", Data: "first"},
+		{Type: "score", Data: "multi\nline"},
+		{Type: "message", Data: ""},
+	}
+	require.NoError(j.Store(events[0], ""))
+	require.NoError(j.Store(events[1], "2"))
+	require.NoError(j.Store(events[2], "2"))
+	require.NoError(j.Close())
+	// Replayed events have the last event ID they were stored with
+	events[1].ID, events[2].ID = "2", "2"
+
+	j, err = NewJournal(path)
+	require.NoError(err)
+	defer j.Close()
+
+	recovered, lastEventID, err := j.Recover()
+	require.NoError(err)
+	assert.Equal(events, recovered)
+	assert.Equal("2", lastEventID)
+
+	lastEventID, err = j.LastEventID()
+	require.NoError(err)
+	assert.Equal("2", lastEventID)
+}
+
+func TestJournalPartialWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	require.NoError(t, os.WriteFile(path, []byte("id: 1\nevent: message\ndata: foo\n\nid: 2\nevent: message\ndata: ba"), 0644))
+
+	j, err := NewJournal(path)
+	require.NoError(t, err)
+	defer j.Close()
+
+	events, lastEventID, err := j.Recover()
+	require.NoError(t, err)
+	assert.Equal(t, []sse.Event{{Type: "message", Data: "foo", ID: "1"}}, events)
+	assert.Equal(t, "1", lastEventID)
+}
+
+func TestJournalLargeEvent(t *testing.T) {
+	require := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "journal")
+	j, err := NewJournal(path)
+	require.NoError(err)
+	defer j.Close()
+
+	large := sse.Event{Type: "message", Data: strings.Repeat("a", 100*1024), ID: "1"}
+	require.NoError(j.Store(large, "1"))
+	require.NoError(j.Store(sse.Event{Type: "message", Data: "small"}, "2"))
+
+	events, lastEventID, err := j.Recover()
+	require.NoError(err)
+	assert.Equal(t, []sse.Event{large, {Type: "message", Data: "small", ID: "2"}}, events)
+	assert.Equal(t, "2", lastEventID)
+
+	lastEventID, err = sse.LastEventIDStore(j).LastEventID()
+	require.NoError(err)
+	assert.Equal(t, "2", lastEventID)
+}
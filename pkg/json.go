@@ -0,0 +1,42 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnmarshal is returned, wrapping the error from encoding/json, when the data of an event isn't valid JSON for the target type
+var ErrUnmarshal = errors.New("unmarshaling event data")
+
+// JSONEventStream decodes the data of each event of a Stream as JSON
+type JSONEventStream[T any] struct {
+	stream Stream
+}
+
+// JSONStream constructs a JSONEventStream decoding the events of s into T
+func JSONStream[T any](s Stream) *JSONEventStream[T] {
+	return &JSONEventStream[T]{stream: s}
+}
+
+// Next decodes the data of the next event
+//
+// io.EOF is returned once the stream closes.
+func (j *JSONEventStream[T]) Next(ctx context.Context) (T, error) {
+	var v T
+	select {
+	case <-ctx.Done():
+		return v, ctx.Err()
+	case event, ok := <-j.stream.Events():
+		if !ok {
+			return v, io.EOF
+		}
+		if err := json.Unmarshal([]byte(event.Data), &v); err != nil {
+			return v, fmt.Errorf("%w: %w", ErrUnmarshal, err)
+		}
+		return v, nil
+	}
+}
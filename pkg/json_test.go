@@ -0,0 +1,42 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONStream(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	type score struct {
+		Exam      int     `json:"exam"`
+		StudentID string  `json:"studentId"`
+		Score     float64 `json:"score"`
+	}
+
+	s := JSONStream[score](newTestStream(`data: {"exam": 3, "studentId": "foo", "score": 0.991}
+
+data: not json
+
+`))
+	ctx := context.Background()
+
+	v, err := s.Next(ctx)
+	require.NoError(err)
+	assert.Equal(score{Exam: 3, StudentID: "foo", Score: 0.991}, v)
+
+	_, err = s.Next(ctx)
+	assert.True(errors.Is(err, ErrUnmarshal))
+	var syntaxErr *json.SyntaxError
+	assert.True(errors.As(err, &syntaxErr))
+
+	_, err = s.Next(ctx)
+	assert.Equal(io.EOF, err)
+}
@@ -0,0 +1,99 @@
+package sse
+
+import (
+	"io"
+	"math/rand"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// LBStrategy selects which of several resources a load balanced Stream connects to
+type LBStrategy int
+
+const (
+	// RoundRobin connects to each resource in turn
+	RoundRobin LBStrategy = iota
+	// Random connects to a random resource
+	Random
+	// LeastConnections connects to the resource with the fewest open connections from load balanced streams in this process
+	LeastConnections
+)
+
+// activeConnections counts the open connections to each resource made by load balanced streams
+var activeConnections = struct {
+	sync.Mutex
+	count map[string]int
+}{count: make(map[string]int)}
+
+type loadBalancer struct {
+	mu        sync.Mutex
+	resources []string
+	strategy  LBStrategy
+	next      int
+}
+
+// NewLoadBalanced constructs a Stream that connects to one of resources chosen by strategy
+//
+// The strategy selects a resource every time the stream connects.
+func NewLoadBalanced(resources []string, strategy LBStrategy, opts ...Option) (Stream, error) {
+	if len(resources) == 0 {
+		return Stream{}, errors.New("no resources")
+	}
+
+	lb := &loadBalancer{resources: resources, strategy: strategy}
+	return New(resources[0], append(opts, func(s *Stream) { s.loadBalancer = lb })...)
+}
+
+func (lb *loadBalancer) pick() string {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	switch lb.strategy {
+	case Random:
+		return lb.resources[rand.Intn(len(lb.resources))]
+	case LeastConnections:
+		activeConnections.Lock()
+		defer activeConnections.Unlock()
+
+		least := lb.resources[0]
+		for _, resource := range lb.resources[1:] {
+			if activeConnections.count[resource] < activeConnections.count[least] {
+				least = resource
+			}
+		}
+		return least
+	}
+
+	resource := lb.resources[lb.next]
+	lb.next = (lb.next + 1) % len(lb.resources)
+	return resource
+}
+
+// track counts body as an open connection to resource until it's closed
+func (lb *loadBalancer) track(resource string, body io.ReadCloser) io.ReadCloser {
+	activeConnections.Lock()
+	activeConnections.count[resource]++
+	activeConnections.Unlock()
+
+	return &trackedConnection{ReadCloser: body, resource: resource}
+}
+
+type trackedConnection struct {
+	io.ReadCloser
+	resource string
+	once     sync.Once
+}
+
+func (c *trackedConnection) Close() error {
+	c.once.Do(func() {
+		activeConnections.Lock()
+		defer activeConnections.Unlock()
+
+		activeConnections.count[c.resource]--
+		if activeConnections.count[c.resource] == 0 {
+			delete(activeConnections.count, c.resource)
+		}
+	})
+	return c.ReadCloser.Close()
+}
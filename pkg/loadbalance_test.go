@@ -0,0 +1,60 @@
+package sse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadBalancerRoundRobin(t *testing.T) {
+	lb := &loadBalancer{resources: []string{"a", "b", "c"}, strategy: RoundRobin}
+
+	var picked []string
+	for i := 0; i < 4; i++ {
+		picked = append(picked, lb.pick())
+	}
+	assert.Equal(t, []string{"a", "b", "c", "a"}, picked)
+}
+
+func TestLoadBalancerRandom(t *testing.T) {
+	lb := &loadBalancer{resources: []string{"a", "b", "c"}, strategy: Random}
+
+	for i := 0; i < 10; i++ {
+		assert.Contains(t, lb.resources, lb.pick())
+	}
+}
+
+func TestLoadBalancerLeastConnections(t *testing.T) {
+	lb := &loadBalancer{resources: []string{"lb-test-a", "lb-test-b"}, strategy: LeastConnections}
+
+	first := lb.track(lb.pick(), http.NoBody)
+	assert.Equal(t, "lb-test-b", lb.pick())
+	second := lb.track(lb.pick(), http.NoBody)
+	assert.Equal(t, "lb-test-a", lb.pick())
+
+	first.Close()
+	first.Close()
+	assert.Equal(t, "lb-test-a", lb.pick())
+	second.Close()
+}
+
+func TestNewLoadBalanced(t *testing.T) {
+	newServer := func(data string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("data: " + data + "\n\n"))
+		}))
+	}
+	a, b := newServer("a"), newServer("b")
+	defer a.Close()
+	defer b.Close()
+
+	s, err := NewLoadBalanced([]string{b.URL, a.URL}, RoundRobin, WithMaxReconnectAttempts(0))
+	require.NoError(t, err)
+	assert.Equal(t, "b", (<-s.Events()).Data)
+
+	_, err = NewLoadBalanced(nil, RoundRobin)
+	assert.Error(t, err)
+}
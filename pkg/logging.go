@@ -0,0 +1,28 @@
+package sse
+
+import "log/slog"
+
+// WithLogger logs what the stream does to logger at debug level: connection attempts, dispatched events, reconnections
+// and errors. Problems with the stream, like one that isn't UTF-8, are logged at warn level. Without it the stream
+// doesn't log anything, including to the log package.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Stream) {
+		s.logger = logger
+	}
+}
+
+// debug logs msg with attrs if the stream has a logger
+func (s Stream) debug(msg string, attrs ...slog.Attr) {
+	if s.logger == nil {
+		return
+	}
+	s.logger.LogAttrs(s.ctx, slog.LevelDebug, msg, attrs...)
+}
+
+// warn logs msg with attrs at warn level if the stream has a logger
+func (s Stream) warn(msg string, attrs ...slog.Attr) {
+	if s.logger == nil {
+		return
+	}
+	s.logger.LogAttrs(s.ctx, slog.LevelWarn, msg, attrs...)
+}
@@ -0,0 +1,77 @@
+package sse
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLogger(t *testing.T) {
+	require := require.New(t)
+
+	var connections int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connections++
+		if connections > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("retry: 1\nevent: score\ndata: 1\n\n"))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	s, err := New(server.URL, WithLogger(logger), WithMaxReconnectAttempts(1))
+	require.NoError(err)
+	for range s.Events() {
+	}
+
+	var records []map[string]any
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var record map[string]any
+		require.NoError(decoder.Decode(&record))
+		assert.Equal(t, "DEBUG", record["level"])
+		delete(record, "time")
+		delete(record, "level")
+		records = append(records, record)
+	}
+
+	assert.Equal(t, []map[string]any{
+		{"msg": "connecting", "resource": server.URL, "attempt": 0.0},
+		{"msg": "dispatching event", "event_type": "score", "id": ""},
+		{"msg": "reconnecting", "resource": server.URL, "attempt": 1.0, "reconnection_time_ms": 1.0},
+		{"msg": "connecting", "resource": server.URL, "attempt": 1.0},
+		{"msg": "error", "resource": server.URL, "error": "unexpected status code 503"},
+	}, records)
+}
+
+func TestWithoutLogger(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream; charset=windows-1252")
+		w.Header().Set("X-Forwarded-For", "203.0.113.1")
+		w.Write([]byte("data: caf\xe9\n\n"))
+	}))
+	defer server.Close()
+
+	// A tunnel, an HTTP version mismatch and a stream that isn't UTF-8 would all be logged with a logger
+	s, err := New(server.URL, WithTunnelDetection(), WithHTTPVersion(HTTP2), WithCharsetDetection(), WithMaxReconnectAttempts(0))
+	require.NoError(t, err)
+	for range s.Events() {
+	}
+	assert.True(t, s.IsTunneled())
+	assert.Empty(t, logs.String())
+}
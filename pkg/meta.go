@@ -0,0 +1,13 @@
+package sse
+
+// SetMeta attaches a value to the stream under key
+//
+// Metadata is shared by every copy of the stream and is safe for concurrent use.
+func (s Stream) SetMeta(key string, val any) {
+	s.meta.Store(key, val)
+}
+
+// GetMeta returns the value attached to the stream under key
+func (s Stream) GetMeta(key string) (any, bool) {
+	return s.meta.Load(key)
+}
@@ -0,0 +1,20 @@
+package sse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeta(t *testing.T) {
+	s := newStream("")
+	copied := s
+
+	_, ok := s.GetMeta("client-ip")
+	assert.False(t, ok)
+
+	copied.SetMeta("client-ip", "127.0.0.1")
+	val, ok := s.GetMeta("client-ip")
+	assert.True(t, ok)
+	assert.Equal(t, "127.0.0.1", val)
+}
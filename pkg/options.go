@@ -0,0 +1,222 @@
+package sse
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Option configures a Stream
+type Option func(*Stream)
+
+var defaultOptions struct {
+	mu   sync.RWMutex
+	opts []Option
+}
+
+// SetDefaultOptions sets options applied to every stream constructed by New before the options passed to it,
+// so those take precedence.
+//
+// The options replace any set before. This is deliberately allowed, rather than only setting them once, so tests can
+// change the defaults and undo them with ResetDefaultOptions. Streams already constructed keep the options they were
+// constructed with. As the defaults apply to every stream in the program, including those constructed by other
+// packages, programs should set them once during initialization, before constructing any stream.
+func SetDefaultOptions(opts ...Option) {
+	defaultOptions.mu.Lock()
+	defer defaultOptions.mu.Unlock()
+
+	defaultOptions.opts = append([]Option(nil), opts...)
+}
+
+// ResetDefaultOptions removes the options set by SetDefaultOptions
+func ResetDefaultOptions() {
+	SetDefaultOptions()
+}
+
+// withDefaultOptions returns the default options followed by opts
+func withDefaultOptions(opts []Option) []Option {
+	defaultOptions.mu.RLock()
+	defer defaultOptions.mu.RUnlock()
+
+	if len(defaultOptions.opts) == 0 {
+		return opts
+	}
+	return append(append([]Option(nil), defaultOptions.opts...), opts...)
+}
+
+// WithStateChangeHook sets a function that is called whenever the ready state of the stream changes.
+//
+// err carries the reason for the transition, if any.
+func WithStateChangeHook(fn func(from, to ReadyState, err error)) Option {
+	return func(s *Stream) {
+		s.stateChangeHook = fn
+	}
+}
+
+// LastEventIDStore persists the last event ID of a stream so it can be resumed after a restart
+type LastEventIDStore interface {
+	// LastEventID returns the ID sent in the Last-Event-ID header of the first connection
+	LastEventID() (string, error)
+	// Store is called after each event is dispatched with the last event ID of the stream
+	Store(event Event, lastEventID string) error
+}
+
+// WithLastEventIDStore resumes the stream from the ID returned by store and records every dispatched event in it.
+//
+// An error from store stops the stream.
+func WithLastEventIDStore(store LastEventIDStore) Option {
+	return func(s *Stream) {
+		s.lastEventIDStore = store
+	}
+}
+
+// WithInitialLastEventID sends id in the Last-Event-ID header of the first connection, resuming the stream from it.
+//
+// The ID returned by a LastEventIDStore takes precedence if it isn't empty.
+func WithInitialLastEventID(id string) Option {
+	return func(s *Stream) {
+		s.lastEventID.Reset()
+		s.lastEventID.WriteString(id)
+	}
+}
+
+// WithEventProcessingTimeout drops events that aren't received from the channel returned by Stream.Events within d.
+//
+// Dropped events are counted by Stream.EventProcessingTimeouts.
+func WithEventProcessingTimeout(d time.Duration) Option {
+	return func(s *Stream) {
+		s.eventProcessingTimeout = d
+	}
+}
+
+// WithDispatchTimeout closes the stream with ErrDispatchTimeout when an event isn't received from the channel it's
+// sent on within d, rather than waiting for it to be received forever.
+//
+// See WithEventProcessingTimeout to drop the event and carry on instead.
+func WithDispatchTimeout(d time.Duration) Option {
+	return func(s *Stream) {
+		s.dispatchTimeout = d
+	}
+}
+
+// WithHTTPTrace attaches trace to the context of every request made by the stream
+func WithHTTPTrace(trace *httptrace.ClientTrace) Option {
+	return func(s *Stream) {
+		s.httpTrace = trace
+	}
+}
+
+// Decoder reads events from a resource that isn't in the event stream format
+type Decoder interface {
+	// Decode returns the next event, or io.EOF at the end of the resource
+	Decode() (Event, error)
+}
+
+// WithDecoder parses the resource with the Decoder returned by fn instead of as an event stream
+func WithDecoder(fn func(io.Reader) Decoder) Option {
+	return func(s *Stream) {
+		s.newDecoder = fn
+	}
+}
+
+// WithRawFrameLogger calls fn with the bytes of each frame of the event stream, up to and including the blank line ending it,
+// before the frame is parsed. Line endings are as received.
+func WithRawFrameLogger(fn func(frame []byte)) Option {
+	return func(s *Stream) {
+		s.rawFrameLogger = fn
+	}
+}
+
+// WithDispatchOnEOF dispatches the event being parsed when a connection ends cleanly before the blank line ending it,
+// so a stream that doesn't end with a blank line still delivers its last event.
+//
+// By default the incomplete event is discarded, as the specification requires: "If the file ends in the middle of an
+// event, before the final empty line, the incomplete event is not dispatched."
+// https://www.w3.org/TR/2015/REC-eventsource-20150203/#event-stream-interpretation
+func WithDispatchOnEOF() Option {
+	return func(s *Stream) {
+		s.dispatchOnEOF = true
+	}
+}
+
+// WithScannerBufferSize reads lines into a buffer of initial bytes, growing it to read lines up to max bytes long.
+//
+// The stream's connection ends with bufio.ErrTooLong when a line is longer than max. The default is 64KB growing up to 1MB.
+func WithScannerBufferSize(initial, max int) Option {
+	return func(s *Stream) {
+		if initial <= 0 || max < initial {
+			s.optionErr = errors.Errorf("invalid scanner buffer size %d up to %d", initial, max)
+			return
+		}
+		s.scannerBufferSize, s.maxScannerBufferSize = initial, max
+	}
+}
+
+// WithChannelBuffer buffers up to n events in the channel returned by Stream.Events. n can't be negative.
+func WithChannelBuffer(n int) Option {
+	return func(s *Stream) {
+		if n < 0 {
+			s.optionErr = errors.Errorf("invalid channel buffer size %d", n)
+			return
+		}
+		s.events = make(chan Event, n)
+	}
+}
+
+// WithHeaders adds headers to every request made by the stream, replacing any the stream sets with the same name.
+//
+// Headers from multiple WithHeaders and WithHeader options are merged.
+func WithHeaders(headers http.Header) Option {
+	return func(s *Stream) {
+		for name, values := range headers {
+			for _, value := range values {
+				s.addHeader(name, value)
+			}
+		}
+	}
+}
+
+// WithHeader adds a header to every request made by the stream, replacing any the stream sets with the same name
+func WithHeader(key, value string) Option {
+	return func(s *Stream) {
+		s.addHeader(key, value)
+	}
+}
+
+func (s *Stream) addHeader(key, value string) {
+	if s.headers == nil {
+		s.headers = make(http.Header)
+	}
+	s.headers.Add(key, value)
+}
+
+// WithZeroCopy makes the Data of each event share memory with the buffer it was parsed into instead of being copied.
+//
+// The stream allocates a new buffer for the next event rather than reusing it, so Data is safe to retain,
+// but it keeps the whole buffer, which may be larger than Data, alive for as long as it is retained.
+func WithZeroCopy() Option {
+	return func(s *Stream) {
+		s.zeroCopy = true
+	}
+}
+
+// WithTimeout closes the stream, without reconnecting, once d has elapsed since the first connection was established
+func WithTimeout(d time.Duration) Option {
+	return func(s *Stream) {
+		s.timeout = d
+	}
+}
+
+// WithReconnectInterval replaces the exponential backoff between reconnection attempts with the interval fn returns.
+//
+// attempt counts the attempts since the connection ended, starting at 1. serverHint is the reconnection time
+// sent by the server in a retry field, or 3 seconds. The stream closes instead if fn returns a negative interval.
+func WithReconnectInterval(fn func(attempt int, serverHint time.Duration) time.Duration) Option {
+	return func(s *Stream) {
+		s.reconnectInterval = fn
+	}
+}
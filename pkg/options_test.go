@@ -0,0 +1,242 @@
+package sse
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	lastEventID string
+	events      []Event
+}
+
+func (m *memoryStore) LastEventID() (string, error) {
+	return m.lastEventID, nil
+}
+
+func (m *memoryStore) Store(event Event, lastEventID string) error {
+	m.events = append(m.events, event)
+	m.lastEventID = lastEventID
+	return nil
+}
+
+func TestSetDefaultOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	SetDefaultOptions(WithZeroCopy(), WithTimeout(time.Hour))
+	defer ResetDefaultOptions()
+
+	s, err := New(server.URL, WithTimeout(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, s.zeroCopy)
+	assert.Equal(t, time.Minute, s.timeout)
+
+	ResetDefaultOptions()
+	s, err = New(server.URL)
+	require.NoError(t, err)
+	assert.False(t, s.zeroCopy)
+	assert.Zero(t, s.timeout)
+}
+
+func TestWithLastEventIDStore(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("1", r.Header.Get("Last-Event-ID"))
+		w.Write([]byte("id: 2\ndata: foo\n\n"))
+	}))
+	defer server.Close()
+
+	store := &memoryStore{lastEventID: "1"}
+	s, err := New(server.URL, WithLastEventIDStore(store), WithMaxReconnectAttempts(0))
+	require.NoError(err)
+
+	for range s.Events() {
+	}
+
+	assert.Equal([]Event{{Type: "message", Data: "foo", ID: "2"}}, store.events)
+	assert.Equal("2", store.lastEventID)
+}
+
+func TestWithInitialLastEventID(t *testing.T) {
+	var lastEventIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+	}))
+	defer server.Close()
+
+	for _, store := range []*memoryStore{nil, {}, {lastEventID: "2"}} {
+		opts := []Option{WithInitialLastEventID("1"), WithMaxReconnectAttempts(0)}
+		if store != nil {
+			opts = append(opts, WithLastEventIDStore(store))
+		}
+		s, err := New(server.URL, opts...)
+		require.NoError(t, err)
+		for range s.Events() {
+		}
+	}
+
+	assert.Equal(t, []string{"1", "1", "2"}, lastEventIDs)
+}
+
+func TestWithEventProcessingTimeout(t *testing.T) {
+	s := newStream("", WithEventProcessingTimeout(time.Millisecond), WithMaxReconnectAttempts(0))
+
+	require.NoError(t, s.parse(ioutil.NopCloser(strings.NewReader("data: foo\n\ndata: bar\n\n"))))
+
+	assert.Equal(t, uint64(2), s.EventProcessingTimeouts())
+}
+
+func TestWithDispatchTimeout(t *testing.T) {
+	c := NewMockClock(time.Now())
+	s := newStream("", WithClock(c), WithDispatchTimeout(time.Second), WithMaxReconnectAttempts(0))
+	parsed := make(chan error)
+	go func() {
+		parsed <- s.parse(ioutil.NopCloser(strings.NewReader("data: foo\n\ndata: bar\n\n")))
+	}()
+
+	assert.Equal(t, "foo", (<-s.Events()).Data)
+	require.Eventually(t, func() bool { return c.Waiters() == 1 }, time.Second, time.Millisecond)
+	c.Advance(time.Second)
+	assert.ErrorIs(t, <-parsed, ErrDispatchTimeout)
+	assert.ErrorIs(t, <-s.Errors(), ErrDispatchTimeout)
+	assert.Equal(t, Closed, s.State())
+}
+
+func TestWithRawFrameLogger(t *testing.T) {
+	var frames []string
+	s := newStream("", WithMaxReconnectAttempts(0), WithRawFrameLogger(func(frame []byte) {
+		frames = append(frames, string(frame))
+	}))
+	go s.parse(ioutil.NopCloser(strings.NewReader("data: a\r\n: comment\r\n\r\nevent: b\rdata: c\r\rdata: d\n\n")))
+
+	var data []string
+	for event := range s.Events() {
+		data = append(data, event.Data)
+	}
+	assert.Equal(t, []string{"a", "c", "d"}, data)
+	assert.Equal(t, []string{"data: a\r\n: comment\r\n\r\n", "event: b\rdata: c\r\r", "data: d\n\n"}, frames)
+}
+
+func TestWithHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Equal(t, []string{"no-store"}, r.Header.Values("Cache-Control"))
+		assert.Equal(t, []string{"a", "b"}, r.Header.Values("X-Custom-Token"))
+	}))
+	defer server.Close()
+
+	s, err := New(server.URL, WithMaxReconnectAttempts(0), WithHeaders(http.Header{
+		"authorization": {"Bearer token"},
+		"Cache-Control": {"no-store"},
+	}), WithHeader("x-custom-token", "a"), WithHeaders(http.Header{"X-Custom-Token": {"b"}}))
+	require.NoError(t, err)
+	for range s.Events() {
+	}
+}
+
+func TestWithChannelBuffer(t *testing.T) {
+	assert.Equal(t, 0, cap(newStream("").Events()))
+	assert.Equal(t, 8, cap(newStream("", WithChannelBuffer(8)).Events()))
+
+	_, err := New("http://localhost", WithChannelBuffer(-1))
+	assert.EqualError(t, err, "invalid channel buffer size -1")
+}
+
+func TestWithHTTPTrace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: foo\n\n"))
+	}))
+	defer server.Close()
+
+	var gotFirstResponseByte bool
+	s, err := New(server.URL, WithMaxReconnectAttempts(0), WithHTTPTrace(&httptrace.ClientTrace{
+		GotFirstResponseByte: func() { gotFirstResponseByte = true },
+	}))
+	require.NoError(t, err)
+
+	for range s.Events() {
+	}
+
+	assert.True(t, gotFirstResponseByte)
+}
+
+func TestWithTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+				w.Write([]byte("data: tick\n\n"))
+				w.(http.Flusher).Flush()
+			}
+		}
+	}))
+	defer server.Close()
+
+	closed := make(chan error, 1)
+	s, err := New(server.URL, WithTimeout(50*time.Millisecond), WithStateChangeHook(func(from, to ReadyState, err error) {
+		if to == Closed {
+			closed <- err
+		}
+	}))
+	require.NoError(t, err)
+
+	for range s.Events() {
+	}
+	assert.Equal(t, ErrStreamTimeout, <-closed)
+}
+
+func TestWithReconnectInterval(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var connections int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connections++
+		switch connections {
+		case 1:
+			w.Write([]byte("retry: 5\nid: 1\ndata: a\n\n"))
+		case 2:
+			assert.Equal("1", r.Header.Get("Last-Event-ID"))
+			w.Write([]byte("data: b\n\n"))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	type call struct {
+		attempt    int
+		serverHint time.Duration
+	}
+	var calls []call
+	s, err := New(server.URL, WithReconnectInterval(func(attempt int, serverHint time.Duration) time.Duration {
+		calls = append(calls, call{attempt, serverHint})
+		if attempt > 1 {
+			return -1
+		}
+		return time.Millisecond
+	}))
+	require.NoError(err)
+
+	var data []string
+	for event := range s.Events() {
+		data = append(data, event.Data)
+	}
+	assert.Equal([]string{"a", "b"}, data)
+	assert.Equal([]call{{1, 5 * time.Millisecond}, {1, 5 * time.Millisecond}, {2, 5 * time.Millisecond}}, calls)
+	assert.Equal(uint64(2), s.Stats().ConnectCount)
+	assert.Equal(Closed, s.State())
+}
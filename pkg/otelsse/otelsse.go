@@ -0,0 +1,58 @@
+// Package otelsse traces Server-Sent Event streams with OpenTelemetry
+package otelsse
+
+import (
+	"context"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer spans are started with
+const instrumentationName = "github.com/jlburkhead/go-sse/pkg/otelsse"
+
+// WithTracerProvider traces the stream with a tracer from tp.
+//
+// Each connection attempt is traced by an sse.connect span with the resource URL, the attempt number and the HTTP status
+// of the response, and each event received on the connection by a child sse.event span with the event's type and
+// data length. The context of an event's span is set as its Ctx.
+func WithTracerProvider(tp trace.TracerProvider) sse.Option {
+	return sse.WithTracer(tracer{tp.Tracer(instrumentationName)})
+}
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+func (t tracer) Connect(ctx context.Context, resource string, attempt int) (context.Context, func(status int, err error)) {
+	ctx, span := t.tracer.Start(ctx, "sse.connect",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("url.full", resource),
+			attribute.Int("sse.attempt", attempt),
+		),
+	)
+	return ctx, func(status int, err error) {
+		if status != 0 {
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+func (t tracer) Event(ctx context.Context, event sse.Event) (context.Context, func()) {
+	ctx, span := t.tracer.Start(ctx, "sse.event",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("event.type", event.Type),
+			attribute.Int("event.data_length", len(event.Data)),
+		),
+	)
+	return ctx, func() { span.End() }
+}
@@ -0,0 +1,68 @@
+package otelsse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWithTracerProvider(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var connections int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connections++
+		if connections > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("retry: 1\nevent: score\ndata: 10\n\n"))
+	}))
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	s, err := sse.New(server.URL, WithTracerProvider(tp), sse.WithMaxReconnectAttempts(1))
+	require.NoError(err)
+
+	event := <-s.Events()
+	for range s.Events() {
+	}
+
+	spans := recorder.Ended()
+	require.Len(spans, 3)
+	connect, received, reconnect := spans[0], spans[1], spans[2]
+
+	assert.Equal("sse.connect", connect.Name())
+	assert.Equal(trace.SpanKindClient, connect.SpanKind())
+	assert.Subset(connect.Attributes(), []attribute.KeyValue{
+		attribute.String("url.full", server.URL),
+		attribute.Int("sse.attempt", 0),
+		attribute.Int("http.response.status_code", http.StatusOK),
+	})
+
+	assert.Equal("sse.event", received.Name())
+	assert.Equal(connect.SpanContext().SpanID(), received.Parent().SpanID())
+	assert.Subset(received.Attributes(), []attribute.KeyValue{
+		attribute.String("event.type", "score"),
+		attribute.Int("event.data_length", 2),
+	})
+	assert.Equal(received.SpanContext(), trace.SpanContextFromContext(event.Ctx))
+
+	assert.Equal("sse.connect", reconnect.Name())
+	assert.Subset(reconnect.Attributes(), []attribute.KeyValue{
+		attribute.Int("sse.attempt", 1),
+		attribute.Int("http.response.status_code", http.StatusServiceUnavailable),
+	})
+	assert.Equal(codes.Error, reconnect.Status().Code)
+}
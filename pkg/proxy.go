@@ -0,0 +1,64 @@
+package sse
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultProxyDetectionThreshold = time.Second
+
+// WithProxyDetection detects whether a proxy between the stream and the resource is buffering the response.
+//
+// The response is considered buffered if its body starts arriving more than the threshold set by
+// WithProxyDetectionThreshold after its headers. This relies on the server writing an event or comment
+// as soon as the stream opens. The result for the latest connection is reported by Stream.IsProxyBuffered.
+func WithProxyDetection() Option {
+	return func(s *Stream) {
+		s.proxyDetection = true
+	}
+}
+
+// WithProxyDetectionThreshold sets how long the body of a response can lag its headers before WithProxyDetection
+// considers it buffered. The default is one second.
+func WithProxyDetectionThreshold(d time.Duration) Option {
+	return func(s *Stream) {
+		s.proxyDetectionThreshold = d
+	}
+}
+
+// IsProxyBuffered reports whether WithProxyDetection detected a buffering proxy on the latest connection
+func (s Stream) IsProxyBuffered() bool {
+	return s.proxyBuffered.Load()
+}
+
+// detectProxy returns body, recording whether its first bytes lag the response headers by more than the threshold
+func (s Stream) detectProxy(body io.ReadCloser) io.ReadCloser {
+	return &proxyDetectingReader{
+		ReadCloser: body,
+		headersAt:  s.clock.Now(),
+		clock:      s.clock,
+		threshold:  s.proxyDetectionThreshold,
+		buffered:   s.proxyBuffered,
+	}
+}
+
+type proxyDetectingReader struct {
+	io.ReadCloser
+	once      sync.Once
+	headersAt time.Time
+	threshold time.Duration
+	buffered  *atomic.Bool
+	clock     Clock
+}
+
+func (r *proxyDetectingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.once.Do(func() {
+			r.buffered.Store(r.clock.Now().Sub(r.headersAt) > r.threshold)
+		})
+	}
+	return n, err
+}
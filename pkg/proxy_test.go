@@ -0,0 +1,40 @@
+package sse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithProxyDetection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		if r.URL.Query().Has("buffered") {
+			time.Sleep(20 * time.Millisecond)
+		}
+		w.Write([]byte("data: foo\n\n"))
+	}))
+	defer server.Close()
+
+	type testCase struct {
+		query    string
+		buffered bool
+	}
+	testCases := []testCase{
+		{"", false},
+		{"?buffered", true},
+	}
+
+	for _, tc := range testCases {
+		s, err := New(server.URL+tc.query, WithProxyDetection(), WithProxyDetectionThreshold(10*time.Millisecond), WithMaxReconnectAttempts(0))
+		require.NoError(t, err)
+		for range s.Events() {
+		}
+		assert.Equal(t, tc.buffered, s.IsProxyBuffered(), tc.query)
+	}
+}
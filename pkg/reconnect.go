@@ -0,0 +1,211 @@
+package sse
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultReconnectionTime is the reconnection time until the server sends a retry field
+	defaultReconnectionTime = 3000
+	// defaultReconnectBackoffCap is the longest a stream waits between reconnection attempts by default
+	defaultReconnectBackoffCap = 30 * time.Second
+	// defaultReconnectBackoffMultiplier is how much the wait grows after each failed reconnection attempt by default
+	defaultReconnectBackoffMultiplier = 2
+	// minReconnectBackoff is where backoff starts when the server asks to reconnect immediately with retry: 0
+	minReconnectBackoff = 100 * time.Millisecond
+	// reconnectBufferSize is how many reconnect events Stream.Reconnects buffers before dropping them
+	reconnectBufferSize = 16
+)
+
+// ReconnectEvent describes an attempt to reconnect
+type ReconnectEvent struct {
+	// Attempt counts the attempts since the connection ended, starting at 1
+	Attempt int
+	// Delay is how long the stream waits before the attempt
+	Delay time.Duration
+	// Reason is the error that ended the connection or failed the previous attempt, it's nil if the connection ended cleanly
+	Reason error
+	// At is when the stream started waiting
+	At time.Time
+}
+
+// WithMaxReconnectAttempts closes the stream after n consecutive failed attempts to reconnect.
+//
+// The count starts again once a connection is established. 0 disables reconnection, so the stream closes when its
+// connection ends. By default the stream reconnects until it's closed.
+func WithMaxReconnectAttempts(n int) Option {
+	return func(s *Stream) {
+		s.maxReconnectAttempts = n
+	}
+}
+
+// WithMaxRetries is an alias of WithMaxReconnectAttempts
+func WithMaxRetries(n int) Option {
+	return WithMaxReconnectAttempts(n)
+}
+
+// WithReconnectionTime sets the reconnection time used until the server sends a retry field. The default is 3 seconds.
+func WithReconnectionTime(d time.Duration) Option {
+	return func(s *Stream) {
+		s.reconnectionTime.Store(d.Milliseconds())
+	}
+}
+
+// WithReconnectBackoffCap limits the time between reconnection attempts to d.
+//
+// The stream waits the reconnection time sent by the server in a retry field, or 3 seconds, before reconnecting,
+// doubling the wait after each failed attempt up to d. The default cap is 30 seconds, see WithExponentialBackoff.
+// A reconnection time of 0 backs off from 100ms instead.
+func WithReconnectBackoffCap(d time.Duration) Option {
+	return func(s *Stream) {
+		s.reconnectBackoffCap = d
+	}
+}
+
+// WithExponentialBackoff sets the reconnection time used until the server sends a retry field to base, multiplying the
+// wait by multiplier after each failed attempt up to max.
+//
+// The default is WithExponentialBackoff(3*time.Second, 2, 30*time.Second). multiplier must be at least 1.
+func WithExponentialBackoff(base time.Duration, multiplier float64, max time.Duration) Option {
+	return func(s *Stream) {
+		if multiplier < 1 {
+			s.optionErr = errors.Errorf("invalid backoff multiplier %v", multiplier)
+			return
+		}
+		s.reconnectionTime.Store(base.Milliseconds())
+		s.reconnectBackoffMultiplier = multiplier
+		s.reconnectBackoffCap = max
+	}
+}
+
+// WithJitter spreads the wait before each reconnection attempt uniformly over [delay*(1-factor), delay*(1+factor)],
+// so many clients disconnected at once don't all reconnect at the same time.
+//
+// factor must be between 0 and 1, the default is 0, which disables jitter.
+func WithJitter(factor float64) Option {
+	return func(s *Stream) {
+		if factor < 0 || factor > 1 {
+			s.optionErr = errors.Errorf("invalid jitter factor %v", factor)
+			return
+		}
+		s.jitter = factor
+	}
+}
+
+// WithURLRotator reconnects to the resource fn returns for each attempt instead of the resource the stream was constructed with.
+//
+// attempt counts the attempts since the connection ended, starting at 1.
+func WithURLRotator(fn func(attempt int) string) Option {
+	return func(s *Stream) {
+		s.urlRotator = fn
+	}
+}
+
+// Reconnects returns a channel receiving an event each time the stream starts waiting to reconnect
+//
+// Events are dropped while the channel is full, it's closed once the channel returned by Events is.
+func (s Stream) Reconnects() <-chan ReconnectEvent {
+	return s.reconnects
+}
+
+// OnConnectionError sets a function that is called whenever the stream's connection ends or an attempt to reconnect fails,
+// like the onerror callback of the EventSource API.
+//
+// It's called before waiting to reconnect. attempt is 0 when an established connection ends and counts the failed
+// reconnection attempts after that, err is nil if the connection ended cleanly, and willRetry reports whether the stream
+// will try to reconnect. Failing to make the initial connection is returned by New instead, and closing the stream
+// doesn't call fn. Calling OnConnectionError again replaces fn, nil removes it.
+func (s Stream) OnConnectionError(fn func(attempt int, err error, willRetry bool)) {
+	if fn == nil {
+		s.connectionErrorHook.Store(nil)
+		return
+	}
+	s.connectionErrorHook.Store(&fn)
+}
+
+func (s Stream) reportConnectionError(attempt int, err error, willRetry bool) {
+	if fn := s.connectionErrorHook.Load(); fn != nil {
+		(*fn)(attempt, err, willRetry)
+	}
+}
+
+func (s Stream) reportReconnect(event ReconnectEvent) {
+	select {
+	case s.reconnects <- event:
+	default:
+	}
+}
+
+// retryAfterError is returned when a 429 Too Many Requests response has a Retry-After header
+type retryAfterError struct {
+	error
+	delay time.Duration
+}
+
+func (e *retryAfterError) Unwrap() error {
+	return e.error
+}
+
+// parseRetryAfter parses the delay in a Retry-After header, which is in seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if delay := date.Sub(now); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
+
+// reconnectDelay returns how long to wait before a reconnection attempt, or a negative duration to stop reconnecting
+func (s *Stream) reconnectDelay(attempt int) time.Duration {
+	delay := s.baseReconnectDelay(attempt)
+	if delay <= 0 || s.jitter == 0 {
+		return delay
+	}
+	return time.Duration(float64(delay) * (1 - s.jitter + 2*s.jitter*rand.Float64()))
+}
+
+// baseReconnectDelay returns the delay before a reconnection attempt before jitter is applied
+func (s *Stream) baseReconnectDelay(attempt int) time.Duration {
+	serverHint := time.Duration(s.reconnectionTime.Load()) * time.Millisecond
+	if s.reconnectInterval != nil {
+		return s.reconnectInterval(attempt, serverHint)
+	}
+
+	// A retry: 0 hint would otherwise retry a server that's down without ever waiting
+	base := serverHint
+	if base == 0 {
+		base = minReconnectBackoff
+	}
+
+	if s.tunneled.Load() {
+		// Tunnels reconnect quickly, so retry promptly without backing off
+		if base > tunnelReconnectionTime {
+			return tunnelReconnectionTime
+		}
+		return base
+	}
+
+	delay := base
+	for i := 1; i < attempt && delay < s.reconnectBackoffCap; i++ {
+		delay = time.Duration(float64(delay) * s.reconnectBackoffMultiplier)
+	}
+	if delay > s.reconnectBackoffCap {
+		return s.reconnectBackoffCap
+	}
+	return delay
+}
@@ -0,0 +1,322 @@
+package sse
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamReconnects(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var connections int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connections++
+		switch connections {
+		case 1:
+			w.Write([]byte("retry: 1\nid: 1\ndata: a\n\n"))
+		case 2:
+			assert.Equal("1", r.Header.Get("Last-Event-ID"))
+			w.Write([]byte("data: b\n\n"))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	var states []ReadyState
+	s, err := New(server.URL, WithMaxReconnectAttempts(2), WithStateChangeHook(func(from, to ReadyState, err error) {
+		states = append(states, to)
+	}))
+	require.NoError(err)
+
+	var data []string
+	for event := range s.Events() {
+		data = append(data, event.Data)
+	}
+	assert.Equal([]string{"a", "b"}, data)
+	assert.Equal(4, connections)
+	assert.Equal([]ReadyState{Open, Connecting, Open, Connecting, Closed}, states)
+}
+
+func TestStreamErrors(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var connections int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connections++
+		switch connections {
+		case 1:
+			w.Write([]byte("retry: 1\ndata: a\n\n\x80"))
+		case 2:
+			w.Write([]byte("data: b\n\n"))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	s, err := New(server.URL, WithMaxReconnectAttempts(2))
+	require.NoError(err)
+
+	var data []string
+	for event := range s.Events() {
+		data = append(data, event.Data)
+	}
+	assert.Equal([]string{"a", "b"}, data)
+
+	var errs []error
+	for err := range s.Errors() {
+		errs = append(errs, err)
+	}
+	require.Len(errs, 3)
+	assert.Contains(errs[0].Error(), "UTF-8")
+	assert.Contains(errs[1].Error(), "unexpected status code 503")
+	assert.Contains(errs[2].Error(), "unexpected status code 503")
+}
+
+func TestStreamReconnectsChannel(t *testing.T) {
+	var connections int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connections++
+		if connections > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("retry: 1\ndata: a\n\n"))
+	}))
+	defer server.Close()
+
+	s, err := New(server.URL, WithMaxReconnectAttempts(2))
+	require.NoError(t, err)
+	for range s.Events() {
+	}
+
+	var reconnects []ReconnectEvent
+	for event := range s.Reconnects() {
+		assert.False(t, event.At.IsZero())
+		reconnects = append(reconnects, event)
+	}
+	require.Len(t, reconnects, 2)
+	assert.Equal(t, 1, reconnects[0].Attempt)
+	assert.Equal(t, time.Millisecond, reconnects[0].Delay)
+	assert.NoError(t, reconnects[0].Reason)
+	assert.Equal(t, 2, reconnects[1].Attempt)
+	assert.Equal(t, 2*time.Millisecond, reconnects[1].Delay)
+	assert.ErrorContains(t, reconnects[1].Reason, "unexpected status code 503")
+}
+
+func TestStreamOnConnectionError(t *testing.T) {
+	release := make(chan struct{})
+	var connections int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connections++
+		if connections > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("retry: 1\ndata: a\n\n"))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer server.Close()
+
+	s, err := New(server.URL, WithMaxReconnectAttempts(2))
+	require.NoError(t, err)
+
+	type call struct {
+		attempt   int
+		err       error
+		willRetry bool
+	}
+	var calls []call
+	s.OnConnectionError(func(attempt int, err error, willRetry bool) {
+		calls = append(calls, call{attempt, err, willRetry})
+	})
+	close(release)
+	for range s.Events() {
+	}
+
+	require.Len(t, calls, 3)
+	assert.Equal(t, 0, calls[0].attempt)
+	assert.NoError(t, calls[0].err)
+	assert.True(t, calls[0].willRetry)
+	assert.Equal(t, 1, calls[1].attempt)
+	assert.ErrorContains(t, calls[1].err, "unexpected status code 503")
+	assert.True(t, calls[1].willRetry)
+	assert.Equal(t, 2, calls[2].attempt)
+	assert.ErrorContains(t, calls[2].err, "unexpected status code 503")
+	assert.False(t, calls[2].willRetry)
+}
+
+func TestStreamRetryAfter(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var connections atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch connections.Add(1) {
+		case 1:
+			w.Write([]byte("retry: 1\ndata: a\n\n"))
+		case 2:
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 3:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte("data: b\n\n"))
+		}
+	}))
+	defer server.Close()
+
+	c := NewMockClock(time.Now())
+	s, err := New(server.URL, WithClock(c), WithMaxReconnectAttempts(5))
+	require.NoError(err)
+	assert.Equal("a", (<-s.Events()).Data)
+
+	for _, delay := range []time.Duration{time.Millisecond, 120 * time.Second, 4 * time.Millisecond} {
+		reconnect := <-s.Reconnects()
+		assert.Equal(delay, reconnect.Delay)
+		require.Eventually(func() bool { return c.Waiters() == 1 }, time.Second, time.Millisecond)
+		c.Advance(delay)
+	}
+	assert.Equal("b", (<-s.Events()).Data)
+	require.NoError(s.Close())
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	type testCase struct {
+		value string
+		delay time.Duration
+		ok    bool
+	}
+	testCases := []testCase{
+		{"30", 30 * time.Second, true},
+		{"0", 0, true},
+		{"Wed, 01 Jan 2020 00:01:00 GMT", time.Minute, true},
+		{"Tue, 31 Dec 2019 23:59:00 GMT", 0, true},
+		{"-1", 0, false},
+		{"", 0, false},
+		{"soon", 0, false},
+	}
+
+	for _, tc := range testCases {
+		delay, ok := parseRetryAfter(tc.value, now)
+		assert.Equal(t, tc.delay, delay, tc.value)
+		assert.Equal(t, tc.ok, ok, tc.value)
+	}
+}
+
+func TestStreamReconnectDelay(t *testing.T) {
+	s := newStream("", WithReconnectBackoffCap(5*time.Second))
+	assert.Equal(t, 3*time.Second, s.reconnectDelay(1))
+
+	s = newStream("", WithReconnectBackoffCap(5*time.Second), WithReconnectionTime(time.Second))
+	var delays []time.Duration
+	for attempt := 1; attempt <= 5; attempt++ {
+		delays = append(delays, s.reconnectDelay(attempt))
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}, delays)
+}
+
+func TestStreamReconnectDelayRetryZero(t *testing.T) {
+	s := newStream("", WithReconnectBackoffCap(time.Second), WithMaxReconnectAttempts(0))
+	require.NoError(t, s.parse(ioutil.NopCloser(strings.NewReader("retry: 0\n\n"))))
+	var delays []time.Duration
+	for attempt := 1; attempt <= 6; attempt++ {
+		delays = append(delays, s.reconnectDelay(attempt))
+	}
+	assert.Equal(t, []time.Duration{
+		100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second,
+	}, delays)
+
+	s.tunneled.Store(true)
+	assert.Equal(t, 100*time.Millisecond, s.reconnectDelay(5))
+}
+
+func TestStreamRetryField(t *testing.T) {
+	s := newStream("", WithMaxReconnectAttempts(0))
+	require.NoError(t, s.parse(ioutil.NopCloser(strings.NewReader("retry: 3000\n\n"))))
+	assert.Equal(t, 3*time.Second, s.reconnectDelay(1))
+	assert.Equal(t, 6*time.Second, s.reconnectDelay(2))
+
+	s = newStream("", WithMaxReconnectAttempts(0))
+	require.NoError(t, s.parse(ioutil.NopCloser(strings.NewReader("retry: 3s\n\n"))))
+	assert.Equal(t, 3*time.Second, s.reconnectDelay(1), "retry fields that aren't digits are ignored")
+}
+
+func TestWithExponentialBackoff(t *testing.T) {
+	s := newStream("", WithExponentialBackoff(time.Second, 1.5, 4*time.Second))
+	require.NoError(t, s.optionErr)
+	var delays []time.Duration
+	for attempt := 1; attempt <= 5; attempt++ {
+		delays = append(delays, s.reconnectDelay(attempt))
+	}
+	assert.Equal(t, []time.Duration{time.Second, 1500 * time.Millisecond, 2250 * time.Millisecond, 3375 * time.Millisecond, 4 * time.Second}, delays)
+
+	s = newStream("", WithExponentialBackoff(time.Second, 0.5, 4*time.Second))
+	assert.EqualError(t, s.optionErr, "invalid backoff multiplier 0.5")
+}
+
+func TestWithJitter(t *testing.T) {
+	s := newStream("", WithReconnectionTime(time.Second), WithJitter(0.5))
+	require.NoError(t, s.optionErr)
+	delays := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		delay := s.reconnectDelay(1)
+		assert.GreaterOrEqual(t, delay, 500*time.Millisecond)
+		assert.LessOrEqual(t, delay, 1500*time.Millisecond)
+		delays[delay] = true
+	}
+	assert.Greater(t, len(delays), 1)
+
+	s = newStream("", WithReconnectionTime(time.Second))
+	assert.Equal(t, time.Second, s.reconnectDelay(1))
+
+	s = newStream("", WithJitter(1.5))
+	assert.EqualError(t, s.optionErr, "invalid jitter factor 1.5")
+}
+
+func TestWithURLRotator(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	blue := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("retry: 1\ndata: blue\n\n"))
+	}))
+	defer blue.Close()
+	var greenConnections int
+	green := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		greenConnections++
+		if greenConnections > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("data: green\n\n"))
+	}))
+	defer green.Close()
+
+	var attempts []int
+	s, err := New(blue.URL, WithMaxReconnectAttempts(1), WithURLRotator(func(attempt int) string {
+		attempts = append(attempts, attempt)
+		return green.URL
+	}))
+	require.NoError(err)
+
+	var data []string
+	for event := range s.Events() {
+		data = append(data, event.Data)
+	}
+	assert.Equal([]string{"blue", "green"}, data)
+	assert.Equal([]int{1, 1}, attempts)
+}
@@ -0,0 +1,137 @@
+package sse
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Router dispatches events from a Stream to handlers by matching patterns against their content.
+//
+// A pattern is a comma separated list of field:glob clauses where field is type or data and glob may contain * wildcards, e.g. type:score,data:*foo*.
+// An event matches a pattern if it matches every clause. The empty pattern matches every event.
+type Router struct {
+	routes    []route
+	exclusive bool
+	err       error
+}
+
+type route struct {
+	clauses []clause
+	handler func(Event)
+}
+
+type clause struct {
+	field string
+	glob  string
+}
+
+// NewRouter constructs a Router
+//
+// By default every handler with a matching pattern is called.
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// On calls handler for events matching pattern
+func (r *Router) On(pattern string, handler func(Event)) *Router {
+	clauses, err := parsePattern(pattern)
+	if err != nil {
+		if r.err == nil {
+			r.err = err
+		}
+		return r
+	}
+
+	r.routes = append(r.routes, route{clauses: clauses, handler: handler})
+	return r
+}
+
+// Exclusive makes the router call only the first handler, in the order they were added, with a matching pattern
+func (r *Router) Exclusive() *Router {
+	r.exclusive = true
+	return r
+}
+
+// Run dispatches events from s until it closes
+//
+// An error is returned if any pattern is invalid.
+func (r *Router) Run(s Stream) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	for event := range s.Events() {
+		r.route(event)
+	}
+
+	return nil
+}
+
+func (r *Router) route(event Event) {
+	for _, route := range r.routes {
+		if !route.matches(event) {
+			continue
+		}
+		route.handler(event)
+		if r.exclusive {
+			return
+		}
+	}
+}
+
+func parsePattern(pattern string) ([]clause, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+
+	var clauses []clause
+	for _, c := range strings.Split(pattern, ",") {
+		field, glob, ok := strings.Cut(c, ":")
+		if !ok {
+			return nil, errors.Errorf("invalid clause %q in pattern %q", c, pattern)
+		}
+		if field != "type" && field != "data" {
+			return nil, errors.Errorf("unknown field %q in pattern %q", field, pattern)
+		}
+		clauses = append(clauses, clause{field: field, glob: glob})
+	}
+	return clauses, nil
+}
+
+func (r route) matches(event Event) bool {
+	for _, c := range r.clauses {
+		value := event.Type
+		if c.field == "data" {
+			value = event.Data
+		}
+		if !matchGlob(c.glob, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchGlob reports whether value matches glob where * matches any sequence of characters
+func matchGlob(glob, value string) bool {
+	parts := strings.Split(glob, "*")
+	if len(parts) == 1 {
+		return glob == value
+	}
+
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(value, part)
+		if i == -1 {
+			return false
+		}
+		value = value[i+len(part):]
+	}
+
+	return strings.HasSuffix(value, last)
+}
@@ -0,0 +1,70 @@
+package sse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchGlob(t *testing.T) {
+	type testCase struct {
+		glob, value string
+		matches     bool
+	}
+	testCases := []testCase{
+		{"score", "score", true},
+		{"score", "scores", false},
+		{"*", "", true},
+		{"*foo*", "a foo b", true},
+		{"*foo*", "fo", false},
+		{"foo*", "foobar", true},
+		{"*bar", "foobar", true},
+		{"*bar", "barfoo", false},
+		{"a*b*c", "abc", true},
+		{"a*b*c", "aXbYc", true},
+		{"a*b*c", "acb", false},
+		{"ab*ba", "aba", false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.matches, matchGlob(tc.glob, tc.value), "%q %q", tc.glob, tc.value)
+	}
+}
+
+func TestRouter(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	input := "event: score\ndata: foo\n\nevent: score\ndata: bar\n\ndata: foo\n\n"
+
+	var scores, foos, all []string
+	err := NewRouter().
+		On("type:score", func(e Event) { scores = append(scores, e.Data) }).
+		On("data:*foo*", func(e Event) { foos = append(foos, e.Type) }).
+		On("", func(e Event) { all = append(all, e.Data) }).
+		Run(newTestStream(input))
+	require.NoError(err)
+
+	assert.Equal([]string{"foo", "bar"}, scores)
+	assert.Equal([]string{"score", "message"}, foos)
+	assert.Equal([]string{"foo", "bar", "foo"}, all)
+}
+
+func TestRouterExclusive(t *testing.T) {
+	var first, second []string
+	err := NewRouter().
+		Exclusive().
+		On("type:score,data:f*", func(e Event) { first = append(first, e.Data) }).
+		On("type:score", func(e Event) { second = append(second, e.Data) }).
+		Run(newTestStream("event: score\ndata: foo\n\nevent: score\ndata: bar\n\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"foo"}, first)
+	assert.Equal(t, []string{"bar"}, second)
+}
+
+func TestRouterInvalidPattern(t *testing.T) {
+	assert.Error(t, NewRouter().On("id:1", func(Event) {}).Run(newStream("")))
+	assert.Error(t, NewRouter().On("score", func(Event) {}).Run(newStream("")))
+}
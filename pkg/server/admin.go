@@ -0,0 +1,121 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// adminHandler exposes a Handler's clients, statistics and broadcasting over HTTP
+type adminHandler struct {
+	handler *Handler
+	mux     *http.ServeMux
+
+	basicAuth          bool
+	username, password string
+}
+
+// AdminOption configures the handler returned by NewAdminHandler
+type AdminOption func(*adminHandler)
+
+// WithAdminBasicAuth requires requests to the admin API to authenticate with HTTP basic authentication
+func WithAdminBasicAuth(username, password string) AdminOption {
+	return func(a *adminHandler) {
+		a.basicAuth = true
+		a.username, a.password = username, password
+	}
+}
+
+// NewAdminHandler constructs an http.Handler managing h with the endpoints
+//
+//	GET /admin/clients          lists the connected clients
+//	DELETE /admin/clients/{id}  disconnects the clients with an ID
+//	POST /admin/broadcast       sends the JSON event in the body, with id, type and data fields, to every client
+//	GET /admin/metrics          returns the handler's statistics
+func NewAdminHandler(h *Handler, opts ...AdminOption) http.Handler {
+	a := &adminHandler{handler: h, mux: http.NewServeMux()}
+	a.mux.HandleFunc("/admin/clients", a.clients)
+	a.mux.HandleFunc("/admin/clients/", a.disconnect)
+	a.mux.HandleFunc("/admin/broadcast", a.broadcast)
+	a.mux.HandleFunc("/admin/metrics", a.metrics)
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+func (a *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if a.basicAuth && !a.authenticated(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="sse admin"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	a.mux.ServeHTTP(w, r)
+}
+
+func (a *adminHandler) authenticated(r *http.Request) bool {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	usernameOK := subtle.ConstantTimeCompare([]byte(username), []byte(a.username)) == 1
+	passwordOK := subtle.ConstantTimeCompare([]byte(password), []byte(a.password)) == 1
+	return usernameOK && passwordOK
+}
+
+func (a *adminHandler) clients(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	writeJSON(w, a.handler.Clients())
+}
+
+func (a *adminHandler) disconnect(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodDelete) {
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/admin/clients/")
+	if !a.handler.Disconnect(id) {
+		http.Error(w, "unknown client", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *adminHandler) broadcast(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	var event jsonEvent
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		http.Error(w, "invalid event: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.handler.Send(event.event())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *adminHandler) metrics(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	writeJSON(w, a.handler.Stats())
+}
+
+// allowMethod responds with 405 Method Not Allowed unless the request uses method
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
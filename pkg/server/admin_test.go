@@ -0,0 +1,87 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHandler(t *testing.T) {
+	h := NewHandler(WithClientID(func(r *http.Request) string {
+		return r.URL.Query().Get("user")
+	}))
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer server.CloseClientConnections()
+	admin := NewAdminHandler(h)
+
+	alice, err := sse.New(server.URL+"?user=alice", sse.WithMaxReconnectAttempts(0))
+	require.NoError(t, err)
+	bob, err := sse.New(server.URL+"?user=bob", sse.WithMaxReconnectAttempts(0))
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	admin.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/clients", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var clients []ClientInfo
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &clients))
+	assert.Equal(t, []ClientInfo{{ID: "alice"}, {ID: "bob"}}, clients)
+
+	w = httptest.NewRecorder()
+	admin.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/broadcast", strings.NewReader(`{"type":"score","data":"1"}`)))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "1", (<-alice.Events()).Data)
+	assert.Equal(t, "1", (<-bob.Events()).Data)
+
+	w = httptest.NewRecorder()
+	admin.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/clients/alice", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	for range alice.Events() {
+	}
+	assert.Equal(t, []ClientInfo{{ID: "bob"}}, h.Clients())
+
+	w = httptest.NewRecorder()
+	admin.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/clients/alice", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// events are counted once their write returns, which can be after the client receives them
+	assert.Eventually(t, func() bool { return h.Stats().EventsSent == 2 }, time.Second, time.Millisecond)
+	w = httptest.NewRecorder()
+	admin.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var stats HandlerStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, HandlerStats{ConnectedClients: 1, EventsSent: 2}, stats)
+
+	w = httptest.NewRecorder()
+	admin.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/metrics", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestWithAdminBasicAuth(t *testing.T) {
+	admin := NewAdminHandler(NewHandler(), WithAdminBasicAuth("admin", "secret"))
+
+	w := httptest.NewRecorder()
+	admin.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/clients", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+
+	r := httptest.NewRequest(http.MethodGet, "/admin/clients", nil)
+	r.SetBasicAuth("admin", "wrong")
+	w = httptest.NewRecorder()
+	admin.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, "/admin/clients", nil)
+	r.SetBasicAuth("admin", "secret")
+	w = httptest.NewRecorder()
+	admin.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	"strconv"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+)
+
+// WithAutoID sends events that don't have an ID with incrementing IDs, starting at start
+func WithAutoID(start uint64) Option {
+	return func(h *Handler) {
+		h.autoID = true
+		h.nextID.Store(start)
+	}
+}
+
+// assignID gives event the next ID if auto IDs are enabled and it doesn't have one
+func (h *Handler) assignID(event sse.Event) sse.Event {
+	if !h.autoID || event.ID != "" {
+		return event
+	}
+	event.ID = strconv.FormatUint(h.nextID.Add(1)-1, 10)
+	return event
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerWithAutoID(t *testing.T) {
+	h := NewHandler(WithAutoID(10))
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	h.Send(sse.Event{Type: "score", Data: "1"})
+	h.Send(sse.Event{Type: "score", Data: "2", ID: "custom"})
+	h.Send(sse.Event{Type: "score", Data: "3"})
+
+	expected := "id: 10\nevent: score\ndata: 1\n\nid: custom\nevent: score\ndata: 2\n\nid: 11\nevent: score\ndata: 3\n\n"
+	body := make([]byte, len(expected))
+	_, err = io.ReadFull(resp.Body, body)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(body))
+}
+
+func TestMarshalID(t *testing.T) {
+	event := sse.Event{Type: "score", Data: "1", ID: "7"}
+
+	b, err := FormatNDJSON.marshal(event, LF)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"id\":\"7\",\"type\":\"score\",\"data\":\"1\"}\n", string(b))
+
+	b, err = FormatNDJSON.marshal(sse.Event{Data: "1"}, LF)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"type\":\"\",\"data\":\"1\"}\n", string(b))
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+)
+
+// Broker publishes events to the subscribers of named topics
+type Broker struct {
+	mu     sync.RWMutex
+	topics map[string]*Topic
+	// all receives events published to every topic
+	all subscribers
+}
+
+// Topic is a named stream of events within a Broker
+type Topic struct {
+	broker      *Broker
+	name        string
+	subscribers subscribers
+
+	retry    time.Duration
+	retrySet bool
+}
+
+type subscribers map[<-chan sse.Event]chan sse.Event
+
+// NewBroker constructs a Broker
+func NewBroker() *Broker {
+	return &Broker{
+		topics: make(map[string]*Topic),
+		all:    make(subscribers),
+	}
+}
+
+// Topic returns the topic with name, creating it if it doesn't exist
+func (b *Broker) Topic(name string) *Topic {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.topics[name]
+	if !ok {
+		t = &Topic{broker: b, name: name, subscribers: make(subscribers)}
+		b.topics[name] = t
+	}
+	return t
+}
+
+// Name returns the name of the topic
+func (t *Topic) Name() string {
+	return t.name
+}
+
+// SetRetryInterval sets the reconnection time sent in a retry field to each client of Topic.ServeHTTP when it connects
+func (t *Topic) SetRetryInterval(d time.Duration) {
+	t.broker.mu.Lock()
+	defer t.broker.mu.Unlock()
+
+	t.retry, t.retrySet = d, true
+}
+
+// ServeHTTP streams the events published to the topic to the client in the event stream format until the request is cancelled
+//
+// The retry field set by SetRetryInterval is sent once, before any events.
+func (t *Topic) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := w.(http.Flusher); !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	events := t.Subscribe()
+	defer t.Unsubscribe(events)
+
+	t.broker.mu.RLock()
+	retry, retrySet := t.retry, t.retrySet
+	t.broker.mu.RUnlock()
+
+	w.Header().Set("Content-Type", FormatSSE.ContentType())
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	writer := NewEventWriter(w)
+	if retrySet {
+		if err := writer.writeRetry(int(retry.Milliseconds())); err != nil {
+			return
+		}
+	} else {
+		writer.flush()
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			if err := writer.WriteEvent(event); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Publish sends an event to every subscriber of the topic and every wildcard subscriber of the broker
+//
+// The event is dropped for subscribers that aren't keeping up.
+func (t *Topic) Publish(event sse.Event) {
+	t.broker.mu.RLock()
+	defer t.broker.mu.RUnlock()
+
+	t.subscribers.send(event)
+	t.broker.all.send(event)
+}
+
+// Subscribe returns a channel receiving events published to the topic
+func (t *Topic) Subscribe() <-chan sse.Event {
+	t.broker.mu.Lock()
+	defer t.broker.mu.Unlock()
+
+	return t.subscribers.add()
+}
+
+// SubscribeAll returns a channel receiving events published to any topic of the broker
+func (t *Topic) SubscribeAll() <-chan sse.Event {
+	t.broker.mu.Lock()
+	defer t.broker.mu.Unlock()
+
+	return t.broker.all.add()
+}
+
+// Unsubscribe closes a channel returned by Subscribe or SubscribeAll
+func (t *Topic) Unsubscribe(ch <-chan sse.Event) {
+	t.broker.mu.Lock()
+	defer t.broker.mu.Unlock()
+
+	t.subscribers.remove(ch)
+	t.broker.all.remove(ch)
+}
+
+func (s subscribers) add() <-chan sse.Event {
+	ch := make(chan sse.Event, defaultBufferSize)
+	s[ch] = ch
+	return ch
+}
+
+func (s subscribers) remove(ch <-chan sse.Event) {
+	if c, ok := s[ch]; ok {
+		close(c)
+		delete(s, ch)
+	}
+}
+
+func (s subscribers) send(event sse.Event) {
+	for _, ch := range s {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroker(t *testing.T) {
+	assert := assert.New(t)
+
+	b := NewBroker()
+	scores, news := b.Topic("scores"), b.Topic("news")
+	assert.Same(scores, b.Topic("scores"))
+
+	scoresCh := scores.Subscribe()
+	all := news.SubscribeAll()
+
+	score := sse.Event{Type: "score", Data: "1"}
+	headline := sse.Event{Type: "headline", Data: "foo"}
+	scores.Publish(score)
+	news.Publish(headline)
+
+	assert.Equal(score, <-scoresCh)
+	assert.Empty(scoresCh)
+	assert.Equal(score, <-all)
+	assert.Equal(headline, <-all)
+
+	news.Unsubscribe(all)
+	_, ok := <-all
+	assert.False(ok)
+
+	scores.Publish(score)
+	assert.Equal(score, <-scoresCh)
+}
+
+func TestTopicServeHTTP(t *testing.T) {
+	scores := NewBroker().Topic("scores")
+	scores.SetRetryInterval(1500 * time.Millisecond)
+	server := httptest.NewServer(scores)
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	scores.Publish(sse.Event{Type: "score", Data: "1"})
+	scores.Publish(sse.Event{Type: "score", Data: "2"})
+
+	expected := "retry: 1500\n\nevent: score\ndata: 1\n\nevent: score\ndata: 2\n\n"
+	body := make([]byte, len(expected))
+	_, err = io.ReadFull(resp.Body, body)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(body))
+}
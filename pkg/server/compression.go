@@ -0,0 +1,93 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// WithCompressionDictionary compresses the stream with zstd and dict for clients that opt in to it with Compression
+// Dictionary Transport https://www.rfc-editor.org/rfc/rfc9842: they send dcz in their Accept-Encoding header and
+// the SHA-256 of dict in an Available-Dictionary header, whose value is returned by DictionaryHash.
+//
+// dict can be a zstd dictionary, such as one trained on common event payloads with zstd --train, or raw content like
+// sample payloads. Either way it's used as raw content, as Compression Dictionary Transport requires. Clients that
+// don't have the dictionary are sent plain zstd if they accept it, and uncompressed events otherwise.
+// The compressor is flushed after every event.
+func WithCompressionDictionary(dict []byte) Option {
+	return func(h *Handler) {
+		h.compressionDict = dict
+		h.compressionDictHash = DictionaryHash(dict)
+	}
+}
+
+// DictionaryHash returns the Available-Dictionary header a client sends to be sent a stream compressed with dict
+func DictionaryHash(dict []byte) string {
+	hash := sha256.Sum256(dict)
+	return ":" + base64.StdEncoding.EncodeToString(hash[:]) + ":"
+}
+
+// acceptsEncoding reports whether the client's Accept-Encoding header includes coding with a q value above 0
+func acceptsEncoding(r *http.Request, coding string) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(encoding, ";")
+		if strings.EqualFold(strings.TrimSpace(name), coding) {
+			return acceptQuality(params) > 0
+		}
+	}
+	return false
+}
+
+// compressionFor returns the content coding to send a client, with the dictionary to compress it with, if any
+func (h *Handler) compressionFor(r *http.Request) (string, []byte) {
+	if h.compressionDict == nil {
+		return "", nil
+	}
+	if acceptsEncoding(r, "dcz") && r.Header.Get("Available-Dictionary") == h.compressionDictHash {
+		return "dcz", h.compressionDict
+	}
+	if acceptsEncoding(r, "zstd") {
+		return "zstd", nil
+	}
+	return "", nil
+}
+
+// zstdWriter compresses writes to an http.ResponseWriter, flushing both when flushed
+type zstdWriter struct {
+	*zstd.Encoder
+	w http.ResponseWriter
+}
+
+// dczMagic starts dictionary-compressed zstd streams, followed by the SHA-256 of the dictionary
+const dczMagic = "\x5e\x2a\x4d\x18\x20\x00\x00\x00"
+
+// newZstdWriter compresses writes to w, with dict if it isn't nil
+func newZstdWriter(w http.ResponseWriter, dict []byte) (*zstdWriter, error) {
+	opts := []zstd.EOption{zstd.WithEncoderConcurrency(1), zstd.WithLowerEncoderMem(true)}
+	if dict != nil {
+		opts = append(opts, zstd.WithEncoderDictRaw(0, dict))
+	}
+	enc, err := zstd.NewWriter(w, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &zstdWriter{enc, w}, nil
+}
+
+// writeDCZHeader writes the header of a stream compressed with dict in the dcz format
+func writeDCZHeader(w io.Writer, dict []byte) error {
+	hash := sha256.Sum256(dict)
+	_, err := io.WriteString(w, dczMagic+string(hash[:]))
+	return err
+}
+
+func (z *zstdWriter) Flush() {
+	if err := z.Encoder.Flush(); err != nil {
+		return
+	}
+	z.w.(http.Flusher).Flush()
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerWithCompressionDictionary(t *testing.T) {
+	var dict []byte
+	for i := 0; i < 10; i++ {
+		dict = append(dict, `data: {"symbol":"ABC","price":`+strconv.Itoa(i)+`,"currency":"USD"}`+"\n\n"...)
+	}
+
+	h := NewHandler(WithCompressionDictionary(dict))
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	get := func(acceptEncoding, availableDictionary string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		if availableDictionary != "" {
+			req.Header.Set("Available-Dictionary", availableDictionary)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+	dictionary := get("gzip, dcz, zstd", DictionaryHash(dict))
+	// Browsers send zstd without having the dictionary
+	browser := get("gzip, deflate, br, zstd", "")
+	wrongDictionary := get("dcz", DictionaryHash([]byte("other")))
+	refused := get("zstd;q=0", "")
+	for _, resp := range []*http.Response{dictionary, browser, wrongDictionary, refused} {
+		defer resp.Body.Close()
+		assert.Equal(t, "Accept-Encoding, Available-Dictionary", resp.Header.Get("Vary"))
+	}
+	assert.Equal(t, "dcz", dictionary.Header.Get("Content-Encoding"))
+	assert.Equal(t, "zstd", browser.Header.Get("Content-Encoding"))
+	assert.Empty(t, wrongDictionary.Header.Get("Content-Encoding"))
+	assert.Empty(t, refused.Header.Get("Content-Encoding"))
+
+	h.Send(sse.Event{Data: `{"symbol":"ABC","price":1,"currency":"USD"}`})
+	expected := `data: {"symbol":"ABC","price":1,"currency":"USD"}` + "\n\n"
+
+	header := make([]byte, len(dczMagic)+sha256.Size)
+	_, err := io.ReadFull(dictionary.Body, header)
+	require.NoError(t, err)
+	hash := sha256.Sum256(dict)
+	assert.Equal(t, dczMagic+string(hash[:]), string(header))
+	withDict, err := zstd.NewReader(dictionary.Body, zstd.WithDecoderDictRaw(0, dict))
+	require.NoError(t, err)
+	defer withDict.Close()
+	plain, err := zstd.NewReader(browser.Body)
+	require.NoError(t, err)
+	defer plain.Close()
+
+	for _, r := range []io.Reader{withDict, plain, wrongDictionary.Body, refused.Body} {
+		body := make([]byte, len(expected))
+		_, err = io.ReadFull(r, body)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(body))
+	}
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	type testCase struct {
+		acceptEncoding string
+		accepts        bool
+	}
+	testCases := []testCase{
+		{"", false},
+		{"zstd", true},
+		{"gzip, ZSTD", true},
+		{"zstd;q=0.5", true},
+		{"zstd;q=0", false},
+		{"zstd; q=0.0", false},
+		{"zstd;q=0.001", true},
+		{"zstdx", false},
+	}
+	for _, tc := range testCases {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", tc.acceptEncoding)
+		assert.Equal(t, tc.accepts, acceptsEncoding(r, "zstd"), tc.acceptEncoding)
+	}
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+)
+
+type idempotencyKey struct{}
+
+// WithContentDeduplication drops events passed to Handler.Send that have the same type and data as an event sent within window
+//
+// This stops events a publisher sends twice from reaching clients twice.
+// It can be combined with WithIdempotencyKeys, events are dropped if either considers them duplicates.
+func WithContentDeduplication(window time.Duration) Option {
+	return func(h *Handler) {
+		h.contentDedup = newDeduplicator(window, contentKey)
+	}
+}
+
+// contentKey hashes the type and data of event.
+// They're separated by a newline, which can't appear in a type, so different types and data never hash the same input.
+func contentKey(event sse.Event) ([sha256.Size]byte, bool) {
+	return sha256.Sum256([]byte(event.Type + "\n" + event.Data)), true
+}
+
+// WithIdempotencyKeys drops events passed to Handler.Send that have the same idempotency key as an event sent within window,
+// so each event is delivered at most once however many times it's sent.
+//
+// Keys are attached with EventWithIdempotencyKey and sent to clients in an idempotency-key comment.
+// Events without a key aren't deduplicated by key, but are still dropped by WithContentDeduplication if it's also used.
+func WithIdempotencyKeys(window time.Duration) Option {
+	return func(h *Handler) {
+		h.idempotencyDedup = newDeduplicator(window, func(event sse.Event) ([sha256.Size]byte, bool) {
+			key, ok := IdempotencyKey(event)
+			return sha256.Sum256([]byte(key)), ok
+		})
+	}
+}
+
+// duplicate reports whether event was sent within the window of any of the handler's deduplicators
+func (h *Handler) duplicate(event sse.Event) bool {
+	if h.idempotencyDedup != nil && h.idempotencyDedup.duplicate(event) {
+		return true
+	}
+	return h.contentDedup != nil && h.contentDedup.duplicate(event)
+}
+
+// EventWithIdempotencyKey returns a copy of event carrying key in its Ctx
+func EventWithIdempotencyKey(event sse.Event, key string) sse.Event {
+	ctx := event.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	event.Ctx = context.WithValue(ctx, idempotencyKey{}, key)
+	return event
+}
+
+// IdempotencyKey returns the idempotency key of event, if it has one
+func IdempotencyKey(event sse.Event) (string, bool) {
+	if event.Ctx == nil {
+		return "", false
+	}
+	key, ok := event.Ctx.Value(idempotencyKey{}).(string)
+	return key, ok
+}
+
+type deduplicator struct {
+	mu     sync.Mutex
+	window time.Duration
+	// key hashes the part of an event that identifies it, reporting false if it can't be deduplicated
+	key  func(sse.Event) ([sha256.Size]byte, bool)
+	seen map[[sha256.Size]byte]time.Time
+	// order holds the hashes of seen events, oldest first
+	order []seenEvent
+}
+
+type seenEvent struct {
+	hash [sha256.Size]byte
+	at   time.Time
+}
+
+func newDeduplicator(window time.Duration, key func(sse.Event) ([sha256.Size]byte, bool)) *deduplicator {
+	return &deduplicator{window: window, key: key, seen: make(map[[sha256.Size]byte]time.Time)}
+}
+
+// duplicate reports whether an event with the same key was seen within the window, and records the event if it wasn't
+func (d *deduplicator) duplicate(event sse.Event) bool {
+	hash, ok := d.key(event)
+	if !ok {
+		return false
+	}
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for len(d.order) > 0 && now.Sub(d.order[0].at) >= d.window {
+		if d.seen[d.order[0].hash] == d.order[0].at {
+			delete(d.seen, d.order[0].hash)
+		}
+		d.order = d.order[1:]
+	}
+
+	if _, ok := d.seen[hash]; ok {
+		return true
+	}
+	d.seen[hash] = now
+	d.order = append(d.order, seenEvent{hash, now})
+	return false
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeduplicator(t *testing.T) {
+	d := newDeduplicator(50*time.Millisecond, contentKey)
+
+	assert.False(t, d.duplicate(sse.Event{Type: "score", Data: "1"}))
+	assert.True(t, d.duplicate(sse.Event{Type: "score", Data: "1"}))
+	assert.False(t, d.duplicate(sse.Event{Type: "score", Data: "2"}))
+	assert.False(t, d.duplicate(sse.Event{Type: "goal", Data: "1"}))
+
+	time.Sleep(60 * time.Millisecond)
+	assert.False(t, d.duplicate(sse.Event{Type: "score", Data: "1"}))
+	assert.True(t, d.duplicate(sse.Event{Type: "score", Data: "1"}))
+}
+
+func TestDeduplicatorTypeDataBoundary(t *testing.T) {
+	d := newDeduplicator(time.Minute, contentKey)
+
+	assert.False(t, d.duplicate(sse.Event{Type: "ab", Data: "c"}))
+	assert.False(t, d.duplicate(sse.Event{Type: "a", Data: "bc"}))
+}
+
+func TestHandlerWithContentDeduplication(t *testing.T) {
+	h := NewHandler(WithContentDeduplication(time.Minute))
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	s, err := sse.New(server.URL)
+	require.NoError(t, err)
+
+	h.Send(sse.Event{Type: "score", Data: "1"})
+	h.Send(sse.Event{Type: "score", Data: "1"})
+	h.Send(sse.Event{Type: "score", Data: "2"})
+
+	assert.Equal(t, "1", (<-s.Events()).Data)
+	assert.Equal(t, "2", (<-s.Events()).Data)
+}
+
+func TestHandlerWithIdempotencyKeys(t *testing.T) {
+	h := NewHandler(WithIdempotencyKeys(time.Minute))
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	h.Send(EventWithIdempotencyKey(sse.Event{Type: "score", Data: "1"}, "a"))
+	h.Send(EventWithIdempotencyKey(sse.Event{Type: "score", Data: "1"}, "a"))
+	h.Send(EventWithIdempotencyKey(sse.Event{Type: "score", Data: "1"}, "b"))
+	h.Send(sse.Event{Type: "score", Data: "2"})
+	h.Send(sse.Event{Type: "score", Data: "2"})
+
+	expected := ": idempotency-key: a\nevent: score\ndata: 1\n\n" +
+		": idempotency-key: b\nevent: score\ndata: 1\n\n" +
+		"event: score\ndata: 2\n\n" +
+		"event: score\ndata: 2\n\n"
+	body := make([]byte, len(expected))
+	_, err = io.ReadFull(resp.Body, body)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(body))
+}
+
+func TestHandlerWithIdempotencyKeysAndContentDeduplication(t *testing.T) {
+	h := NewHandler(WithIdempotencyKeys(time.Minute), WithContentDeduplication(time.Minute))
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	h.Send(EventWithIdempotencyKey(sse.Event{Type: "score", Data: "1"}, "a"))
+	h.Send(EventWithIdempotencyKey(sse.Event{Type: "score", Data: "1"}, "b"))
+	h.Send(EventWithIdempotencyKey(sse.Event{Type: "score", Data: "2"}, "a"))
+	h.Send(sse.Event{Type: "score", Data: "3"})
+	h.Send(sse.Event{Type: "score", Data: "3"})
+	h.Send(EventWithIdempotencyKey(sse.Event{Type: "score", Data: "4"}, "c"))
+
+	expected := ": idempotency-key: a\nevent: score\ndata: 1\n\n" +
+		"event: score\ndata: 3\n\n" +
+		": idempotency-key: c\nevent: score\ndata: 4\n\n"
+	body := make([]byte, len(expected))
+	_, err = io.ReadFull(resp.Body, body)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(body))
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"time"
+)
+
+// WithDrainTimeout limits how long Handler.Shutdown waits for clients to disconnect
+func WithDrainTimeout(d time.Duration) Option {
+	return func(h *Handler) {
+		h.drainTimeout = d
+	}
+}
+
+// Shutdown drains the handler.
+//
+// New connections are refused with 503 Service Unavailable. Every connected client is sent the events already queued for it,
+// then a final retry: 0 field, and is disconnected. Shutdown returns once every client has disconnected,
+// or with the context's error if ctx is done or the drain timeout elapses first.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	if h.drained == nil {
+		h.drained = make(chan struct{})
+		h.drainTotal = len(h.clients)
+		if len(h.clients) == 0 {
+			close(h.drained)
+		}
+		close(h.shutdown)
+	}
+	drained := h.drained
+	h.mu.Unlock()
+
+	if h.drainTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.drainTimeout)
+		defer cancel()
+	}
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// DrainProgress returns how many of the clients connected when Shutdown was called have disconnected
+func (h *Handler) DrainProgress() (closed, total int) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.drained == nil {
+		return 0, 0
+	}
+	return h.drainTotal - len(h.clients), h.drainTotal
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerShutdown(t *testing.T) {
+	h := NewHandler(WithDrainTimeout(time.Second))
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	closed, total := h.DrainProgress()
+	assert.Equal(t, 0, closed)
+	assert.Equal(t, 0, total)
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	h.Send(sse.Event{Type: "score", Data: "1"})
+	require.NoError(t, h.Shutdown(context.Background()))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "event: score\ndata: 1\n\nretry: 0\n\n", string(body))
+
+	closed, total = h.DrainProgress()
+	assert.Equal(t, 1, closed)
+	assert.Equal(t, 1, total)
+
+	resp, err = http.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
+
+func TestHandlerShutdownTimeout(t *testing.T) {
+	h := NewHandler(WithDrainTimeout(10 * time.Millisecond))
+
+	stuck := &client{id: "stuck", events: make(chan []sse.Event, 1)}
+	h.register(stuck)
+
+	assert.ErrorIs(t, h.Shutdown(context.Background()), context.DeadlineExceeded)
+	closed, total := h.DrainProgress()
+	assert.Equal(t, 0, closed)
+	assert.Equal(t, 1, total)
+
+	h.unregister(stuck)
+	assert.NoError(t, h.Shutdown(context.Background()))
+}
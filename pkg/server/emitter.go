@@ -0,0 +1,107 @@
+package server
+
+import (
+	"sync"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/pkg/errors"
+)
+
+// ErrUnknownClient is returned when emitting to a client that isn't connected
+var ErrUnknownClient = errors.New("unknown client")
+
+// ErrPerClientUnsupported is returned when emitting to a client through an emitter that doesn't know about clients
+var ErrPerClientUnsupported = errors.New("per-client delivery unsupported")
+
+// EventEmitter publishes events without depending on how they're delivered
+type EventEmitter interface {
+	// Emit publishes an event to every client
+	Emit(event sse.Event) error
+	// EmitTo publishes an event to a single client, or returns ErrPerClientUnsupported if the emitter can't address clients
+	EmitTo(clientID string, event sse.Event) error
+}
+
+var (
+	_ EventEmitter = (*Handler)(nil)
+	_ EventEmitter = (*Broker)(nil)
+	_ EventEmitter = (*MockEmitter)(nil)
+)
+
+// Emit sends an event to every connected client
+func (h *Handler) Emit(event sse.Event) error {
+	h.Send(event)
+	return nil
+}
+
+// EmitTo sends an event to the connected clients with an ID
+//
+// ErrUnknownClient is returned if there are none, and the error decrypting the event if it can't be decrypted.
+func (h *Handler) EmitTo(clientID string, event sse.Event) error {
+	event, err := h.decrypt(event)
+	if err != nil {
+		return errors.Wrap(err, "decrypting event")
+	}
+	event = h.assignID(event)
+
+	h.mu.RLock()
+	if len(h.ids[clientID]) == 0 {
+		h.mu.RUnlock()
+		return ErrUnknownClient
+	}
+	dropped := sendAll(h.ids[clientID], event)
+	h.mu.RUnlock()
+
+	h.reportDropped(dropped, event)
+	return nil
+}
+
+// Emit publishes an event to the topic named by its type
+func (b *Broker) Emit(event sse.Event) error {
+	b.Topic(eventType(event)).Publish(event)
+	return nil
+}
+
+// EmitTo returns ErrPerClientUnsupported, a broker's subscribers aren't clients it can address.
+//
+// Publishing to a topic named by the client ID instead would mix client IDs with topic names.
+func (b *Broker) EmitTo(clientID string, event sse.Event) error {
+	return ErrPerClientUnsupported
+}
+
+// MockEmitter records emitted events for tests
+type MockEmitter struct {
+	mu sync.Mutex
+	// Err is returned from Emit and EmitTo if set. Events aren't recorded when it is.
+	Err error
+	// Emitted holds the events passed to Emit
+	Emitted []sse.Event
+	// EmittedTo holds the events passed to EmitTo by client ID
+	EmittedTo map[string][]sse.Event
+}
+
+// Emit records an event
+func (m *MockEmitter) Emit(event sse.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Err != nil {
+		return m.Err
+	}
+	m.Emitted = append(m.Emitted, event)
+	return nil
+}
+
+// EmitTo records an event for a client
+func (m *MockEmitter) EmitTo(clientID string, event sse.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Err != nil {
+		return m.Err
+	}
+	if m.EmittedTo == nil {
+		m.EmittedTo = make(map[string][]sse.Event)
+	}
+	m.EmittedTo[clientID] = append(m.EmittedTo[clientID], event)
+	return nil
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerEmitTo(t *testing.T) {
+	h := NewHandler(WithClientID(func(r *http.Request) string {
+		return r.URL.Query().Get("client")
+	}))
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	alice, err := sse.New(server.URL + "?client=alice")
+	require.NoError(t, err)
+
+	var emitter EventEmitter = h
+	assert.Equal(t, ErrUnknownClient, emitter.EmitTo("bob", sse.Event{Data: "lost"}))
+	require.NoError(t, emitter.EmitTo("alice", sse.Event{Data: "foo"}))
+	require.NoError(t, emitter.Emit(sse.Event{Data: "bar"}))
+
+	assert.Equal(t, "foo", (<-alice.Events()).Data)
+	assert.Equal(t, "bar", (<-alice.Events()).Data)
+}
+
+func TestBrokerEmit(t *testing.T) {
+	b := NewBroker()
+	scores, alice := b.Topic("score").Subscribe(), b.Topic("alice").Subscribe()
+
+	var emitter EventEmitter = b
+	require.NoError(t, emitter.Emit(sse.Event{Type: "score", Data: "1"}))
+	assert.Equal(t, ErrPerClientUnsupported, emitter.EmitTo("alice", sse.Event{Data: "hi"}))
+
+	assert.Equal(t, sse.Event{Type: "score", Data: "1"}, <-scores)
+	assert.Empty(t, alice, "client IDs aren't topics")
+}
+
+func TestMockEmitter(t *testing.T) {
+	m := &MockEmitter{}
+	require.NoError(t, m.Emit(sse.Event{Data: "1"}))
+	require.NoError(t, m.EmitTo("alice", sse.Event{Data: "2"}))
+	assert.Equal(t, []sse.Event{{Data: "1"}}, m.Emitted)
+	assert.Equal(t, map[string][]sse.Event{"alice": {{Data: "2"}}}, m.EmittedTo)
+
+	m.Err = errors.New("failed")
+	assert.Equal(t, m.Err, m.Emit(sse.Event{}))
+}
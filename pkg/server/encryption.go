@@ -0,0 +1,35 @@
+package server
+
+import (
+	sse "github.com/jlburkhead/go-sse/pkg"
+)
+
+// WithDecryption decrypts the data of events encrypted with key by a stream using sse.WithEncryption before sending
+// them to clients, so a handler can relay an encrypted stream.
+//
+// Each event is decrypted once when it's sent, so the history and replayed events hold the decrypted data.
+// Events that can't be decrypted are skipped and reported to the function passed to Handler.OnSendError.
+// key must be 16, 24, or 32 bytes, otherwise Handler.Err returns an error and clients are refused.
+func WithDecryption(key []byte) Option {
+	return func(h *Handler) {
+		d, err := sse.NewDecrypter(key)
+		if err != nil {
+			h.optionErr = err
+			return
+		}
+		h.decrypter = d
+	}
+}
+
+// decrypt returns event with its data decrypted if the handler has a decryption key
+func (h *Handler) decrypt(event sse.Event) (sse.Event, error) {
+	if h.decrypter == nil {
+		return event, nil
+	}
+	data, err := h.decrypter.Decrypt(event.Data)
+	if err != nil {
+		return event, err
+	}
+	event.Data = data
+	return event, nil
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerWithDecryption(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("id: 1\nevent: secret\ndata: the eagle\ndata: has landed\n\n"))
+	}))
+	defer upstream.Close()
+
+	relay, err := sse.New(upstream.URL, sse.WithEncryption(key), sse.WithMaxReconnectAttempts(0))
+	require.NoError(err)
+	encrypted := <-relay.Events()
+	require.NotContains(encrypted.Data, "eagle")
+
+	h := NewHandler(WithDecryption(key))
+	errs := make(chan error, 1)
+	h.OnSendError(func(clientID string, event sse.Event, err error) {
+		errs <- err
+	})
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	s, err := sse.New(server.URL)
+	require.NoError(err)
+	defer s.Close()
+
+	h.Send(sse.Event{Type: "secret", Data: "not encrypted"})
+	h.Send(encrypted)
+	assert.Equal(sse.Event{ID: "1", Type: "secret", Data: "the eagle\nhas landed"}, <-s.Events())
+	assert.ErrorContains(<-errs, "decoding data")
+}
+
+func TestHandlerWithDecryptionInvalidKey(t *testing.T) {
+	assert := assert.New(t)
+
+	h := NewHandler(WithDecryption([]byte("too short")))
+	assert.ErrorContains(h.Err(), "creating cipher")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(http.StatusInternalServerError, w.Code)
+}
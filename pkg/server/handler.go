@@ -0,0 +1,710 @@
+// Package server serves Server-Sent Events https://www.w3.org/TR/2015/REC-eventsource-20150203/
+package server
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultBufferSize       = 16
+	defaultReplayBufferSize = 64
+	// recentIDsSize is how many of the most recent event IDs are remembered to filter the replay buffer
+	recentIDsSize = 1024
+)
+
+// errShuttingDown is returned when a client connects while the handler is shutting down
+var errShuttingDown = errors.New("shutting down")
+
+// ErrClientBufferFull is reported when an event is dropped for a client that isn't keeping up
+var ErrClientBufferFull = errors.New("client buffer full")
+
+// Handler is an http.Handler that streams events to every connected client
+type Handler struct {
+	mu      sync.RWMutex
+	clients map[*client]struct{}
+	groups  map[string]map[*client]struct{}
+	ids     map[string]map[*client]struct{}
+
+	clientID     func(r *http.Request) string
+	nextClientID atomic.Uint64
+	bufferSize   int
+
+	stickyRouting func(r *http.Request) string
+	formats       []EventFormat
+	serializer    func(sse.Event) ([]byte, error)
+	lineEnding    LineEnding
+
+	compressionDict     []byte
+	compressionDictHash string
+	decrypter           *sse.Decrypter
+
+	retryPolicy func(sse.Event) bool
+	// replay holds must-redeliver events, oldest first
+	replay []replayedEvent
+	// seq counts the events broadcast while there's a retry policy
+	seq uint64
+	// recentIDs maps the IDs of the most recent events broadcast to their seq, recentIDOrder holds them oldest first
+	recentIDs     map[string]uint64
+	recentIDOrder []string
+	// lastValues holds the most recent event of each type when the last value cache is enabled
+	lastValues map[string]sse.Event
+
+	contentDedup     *deduplicator
+	idempotencyDedup *deduplicator
+
+	history *sqliteHistory
+
+	autoID bool
+	nextID atomic.Uint64
+
+	onSendError func(clientID string, event sse.Event, err error)
+	metrics     *metrics
+
+	drainTimeout time.Duration
+	// shutdown is closed when the handler starts draining
+	shutdown chan struct{}
+	// drained is closed once every client has disconnected after shutdown
+	drained    chan struct{}
+	drainTotal int
+
+	optionErr error
+}
+
+type client struct {
+	id          string
+	group       string
+	lastEventID string
+	// events queues batches of events to write to the client, flushing its connection after each batch
+	events chan []sse.Event
+	// disconnect is closed to end the client's connection
+	disconnect chan struct{}
+}
+
+// Option configures a Handler
+type Option func(*Handler)
+
+// WithClientBufferSize sets how many events are queued for each client before they're dropped, which is 16 by default.
+//
+// A batch sent with Handler.SendBatch takes the place of a single event.
+// Applying the option panics if n is negative.
+func WithClientBufferSize(n int) Option {
+	return func(h *Handler) {
+		if n < 0 {
+			panic(errors.Errorf("invalid client buffer size %d", n))
+		}
+		h.bufferSize = n
+	}
+}
+
+// WithStickyRouting groups clients by the key fn returns for their request.
+//
+// Events are sent to a group with Handler.SendToGroup.
+func WithStickyRouting(fn func(r *http.Request) string) Option {
+	return func(h *Handler) {
+		h.stickyRouting = fn
+	}
+}
+
+// WithEncodings sets the formats the handler can send events in.
+//
+// The format is negotiated with each client using its Accept header, preferring the media types with the highest q
+// value. Media types with q=0 are never chosen. The first format is used if the client doesn't accept any of them.
+// By default, or if formats is empty, only FormatSSE is used.
+func WithEncodings(formats ...EventFormat) Option {
+	return func(h *Handler) {
+		if len(formats) == 0 {
+			formats = []EventFormat{FormatSSE}
+		}
+		h.formats = formats
+	}
+}
+
+// WithLineEnding sets the line separator of the event stream formats, which is LF by default.
+//
+// Some strict interpretations of the specification require CRLF.
+func WithLineEnding(le LineEnding) Option {
+	return func(h *Handler) {
+		h.lineEnding = le
+	}
+}
+
+// WithEventSerializer replaces the serialization of events sent to clients.
+//
+// The bytes fn returns are written to the client as is. Events fn returns an error for are skipped.
+func WithEventSerializer(fn func(sse.Event) ([]byte, error)) Option {
+	return func(h *Handler) {
+		h.serializer = fn
+	}
+}
+
+// WithEventRetryPolicy marks events passed to Handler.Send for which fn returns true as must-redeliver.
+//
+// The most recent must-redeliver events are kept in a replay buffer and sent to every client when it connects,
+// before any other events, so they survive reconnects. A client reconnecting with the ID of one of the 1024 most
+// recent events isn't sent the ones it already received, otherwise clients may receive them more than once.
+func WithEventRetryPolicy(fn func(sse.Event) bool) Option {
+	return func(h *Handler) {
+		h.retryPolicy = fn
+		h.recentIDs = make(map[string]uint64)
+	}
+}
+
+// WithLastValueCache keeps the most recent event of each type passed to Handler.Send and sends them to every client when it connects,
+// before any other events.
+func WithLastValueCache() Option {
+	return func(h *Handler) {
+		h.lastValues = make(map[string]sse.Event)
+	}
+}
+
+// WithClientID identifies clients by the ID fn returns for their request.
+// By default each client is given a unique ID.
+func WithClientID(fn func(r *http.Request) string) Option {
+	return func(h *Handler) {
+		h.clientID = fn
+	}
+}
+
+// NewHandler constructs a Handler
+func NewHandler(opts ...Option) *Handler {
+	h := &Handler{
+		clients:    make(map[*client]struct{}),
+		groups:     make(map[string]map[*client]struct{}),
+		ids:        make(map[string]map[*client]struct{}),
+		formats:    []EventFormat{FormatSSE},
+		bufferSize: defaultBufferSize,
+		metrics:    new(metrics),
+		shutdown:   make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// Err returns the error applying an invalid option to the handler, if any.
+//
+// A handler with an invalid option refuses every client.
+func (h *Handler) Err() error {
+	return h.optionErr
+}
+
+// ServeHTTP streams events to the client until the request is cancelled or the handler is shut down
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := w.(http.Flusher); !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	if h.optionErr != nil {
+		http.Error(w, "misconfigured handler", http.StatusInternalServerError)
+		return
+	}
+
+	c := &client{events: make(chan []sse.Event, h.bufferSize), disconnect: make(chan struct{})}
+	if h.clientID != nil {
+		c.id = h.clientID(r)
+	} else {
+		c.id = strconv.FormatUint(h.nextClientID.Add(1), 10)
+	}
+	if h.stickyRouting != nil {
+		c.group = h.stickyRouting(r)
+	}
+	c.lastEventID = r.Header.Get("Last-Event-ID")
+	initial, err := h.register(c)
+	if err == errShuttingDown {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		http.Error(w, "loading event history", http.StatusInternalServerError)
+		return
+	}
+	defer h.unregister(c)
+
+	// unregister the client as soon as it disconnects rather than on the next write to it
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-r.Context().Done():
+			h.unregister(c)
+		case <-done:
+		}
+	}()
+
+	format := h.negotiate(r)
+
+	var out io.Writer = w
+	coding, dict := h.compressionFor(r)
+	if coding != "" {
+		z, err := newZstdWriter(w, dict)
+		if err != nil {
+			http.Error(w, "invalid compression dictionary", http.StatusInternalServerError)
+			return
+		}
+		defer z.Close()
+		w.Header().Set("Content-Encoding", coding)
+		out = z
+	}
+	if h.compressionDict != nil {
+		w.Header().Add("Vary", "Accept-Encoding, Available-Dictionary")
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if coding == "dcz" {
+		if err := writeDCZHeader(w, dict); err != nil {
+			return
+		}
+	}
+
+	marshal := func(event sse.Event) ([]byte, error) {
+		return format.marshal(event, h.lineEnding)
+	}
+	if h.serializer != nil {
+		marshal = h.serializer
+	}
+
+	writer := NewEventWriter(out)
+	writer.SetLineEnding(h.lineEnding)
+	writer.flush()
+
+	// write writes a batch of events, flushing once they're all written
+	write := func(events []sse.Event) error {
+		if err := r.Context().Err(); err != nil {
+			return err
+		}
+		defer writer.flush()
+		for _, event := range events {
+			b, err := marshal(event)
+			if err != nil {
+				h.metrics.failed()
+				h.sendError(c.id, event, errors.Wrap(err, "serializing event"))
+				continue
+			}
+			if err := writer.writeUnflushed(b); err != nil {
+				h.metrics.failed()
+				h.sendError(c.id, event, err)
+				return err
+			}
+			h.metrics.sent()
+		}
+		return nil
+	}
+
+	if len(initial) != 0 {
+		if err := write(initial); err != nil {
+			return
+		}
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-c.disconnect:
+			return
+		case events := <-c.events:
+			if err := write(events); err != nil {
+				return
+			}
+		case <-h.shutdown:
+			for len(c.events) > 0 {
+				if err := write(<-c.events); err != nil {
+					return
+				}
+			}
+			if format != FormatNDJSON {
+				_ = writer.writeRetry(0)
+			}
+			return
+		}
+	}
+}
+
+// Send sends an event to every connected client
+//
+// The event is dropped for clients that aren't keeping up.
+func (h *Handler) Send(event sse.Event) {
+	if h.duplicate(event) {
+		return
+	}
+	event, err := h.decrypt(event)
+	if err != nil {
+		h.sendError("", event, errors.Wrap(err, "decrypting event"))
+		return
+	}
+	event = h.assignID(event)
+	events, dropped, err := h.broadcast([]sse.Event{event})
+	if err != nil {
+		h.sendError("", events[0], errors.Wrap(err, "storing event"))
+	}
+	h.reportDropped(dropped, events...)
+}
+
+// SendBatch sends events to every connected client, flushing each client's connection once they're all written rather than after each event.
+//
+// The batch is dropped as a whole for clients that aren't keeping up. Events are sent even if storing them in the
+// history fails, and the first error storing them is returned.
+func (h *Handler) SendBatch(events []sse.Event) error {
+	batch := make([]sse.Event, 0, len(events))
+	for _, event := range events {
+		if h.duplicate(event) {
+			continue
+		}
+		event, err := h.decrypt(event)
+		if err != nil {
+			h.sendError("", event, errors.Wrap(err, "decrypting event"))
+			continue
+		}
+		batch = append(batch, h.assignID(event))
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+
+	batch, dropped, err := h.broadcast(batch)
+	h.reportDropped(dropped, batch...)
+	return errors.Wrap(err, "storing events")
+}
+
+// PublishVersioned sends an event with a version field to every connected client, see Send
+//
+// Clients can drop events with versions they don't support, so the schema of events can evolve.
+func (h *Handler) PublishVersioned(event sse.Event, version int) {
+	event.Version = version
+	h.Send(event)
+}
+
+// broadcast stores a batch of events in the history if there is one and sends it to every connected client,
+// returning the events as they were sent and the clients they were dropped for.
+//
+// The events are sent even if storing them fails, the first error storing them is returned.
+func (h *Handler) broadcast(events []sse.Event) ([]sse.Event, []*client, error) {
+	mustRedeliver := make([]bool, len(events))
+	// Events are stored with the write lock held so they're stored in the order they're sent
+	exclusive := h.lastValues != nil || h.retryPolicy != nil || h.history != nil
+	for i, event := range events {
+		mustRedeliver[i] = h.retryPolicy != nil && h.retryPolicy(event)
+	}
+	if exclusive {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+	} else {
+		h.mu.RLock()
+		defer h.mu.RUnlock()
+	}
+
+	var err error
+	for i, event := range events {
+		// The event is stored with the lock held so clients registering concurrently either replay it or receive it
+		if h.history != nil {
+			var appendErr error
+			if event, appendErr = h.history.append(event); err == nil {
+				err = appendErr
+			}
+			events[i] = event
+		}
+
+		if h.retryPolicy != nil {
+			h.seq++
+			h.trackID(event.ID)
+		}
+		if mustRedeliver[i] {
+			h.replay = append(h.replay, replayedEvent{event, h.seq})
+			if len(h.replay) > defaultReplayBufferSize {
+				h.replay = h.replay[1:]
+			}
+		}
+		if h.lastValues != nil {
+			h.lastValues[eventType(event)] = event
+		}
+	}
+
+	return events, sendAll(h.clients, events...), err
+}
+
+// replayedEvent is a must-redeliver event in the replay buffer
+type replayedEvent struct {
+	event sse.Event
+	seq   uint64
+}
+
+// trackID remembers that the event with id is the latest broadcast, forgetting the oldest ID once there are too many
+func (h *Handler) trackID(id string) {
+	if id == "" {
+		return
+	}
+	if _, ok := h.recentIDs[id]; !ok {
+		h.recentIDOrder = append(h.recentIDOrder, id)
+		if len(h.recentIDOrder) > recentIDsSize {
+			delete(h.recentIDs, h.recentIDOrder[0])
+			h.recentIDOrder = h.recentIDOrder[1:]
+		}
+	}
+	h.recentIDs[id] = h.seq
+}
+
+// replayFor returns the must-redeliver events a client with lastEventID hasn't received
+func (h *Handler) replayFor(lastEventID string) []sse.Event {
+	seq, ok := h.recentIDs[lastEventID]
+	if lastEventID == "" || !ok {
+		seq = 0
+	}
+
+	var events []sse.Event
+	for _, replayed := range h.replay {
+		if replayed.seq > seq {
+			events = append(events, replayed.event)
+		}
+	}
+	return events
+}
+
+// SendToGroup sends an event to every connected client in a sticky group
+//
+// The event is dropped for clients that aren't keeping up.
+func (h *Handler) SendToGroup(key string, event sse.Event) {
+	event, err := h.decrypt(event)
+	if err != nil {
+		h.sendError("", event, errors.Wrap(err, "decrypting event"))
+		return
+	}
+	event = h.assignID(event)
+
+	h.mu.RLock()
+	dropped := sendAll(h.groups[key], event)
+	h.mu.RUnlock()
+
+	h.reportDropped(dropped, event)
+}
+
+// OnSendError calls fn when an event can't be delivered to a client.
+//
+// err is ErrClientBufferFull when the event is dropped because the client isn't keeping up,
+// otherwise it's the error serializing the event or writing it to the client.
+// fn is called without the handler's lock held, so it may send events.
+func (h *Handler) OnSendError(fn func(clientID string, event sse.Event, err error)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.onSendError = fn
+}
+
+func (h *Handler) sendError(clientID string, event sse.Event, err error) {
+	h.mu.RLock()
+	fn := h.onSendError
+	h.mu.RUnlock()
+
+	if fn != nil {
+		fn(clientID, event, err)
+	}
+}
+
+func (h *Handler) reportDropped(dropped []*client, events ...sse.Event) {
+	h.metrics.dropped(len(dropped) * len(events))
+	for _, c := range dropped {
+		for _, event := range events {
+			h.sendError(c.id, event, ErrClientBufferFull)
+		}
+	}
+}
+
+// negotiate returns the first format in the client's Accept header supported by the handler
+func (h *Handler) negotiate(r *http.Request) EventFormat {
+	best, bestQuality := h.formats[0], 0.0
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, _ := strings.Cut(accept, ";")
+		mediaType = strings.TrimSpace(mediaType)
+		quality := acceptQuality(params)
+		if quality <= bestQuality {
+			continue
+		}
+		for _, format := range h.formats {
+			if format.ContentType() == mediaType {
+				best, bestQuality = format, quality
+				break
+			}
+		}
+	}
+	return best
+}
+
+// acceptQuality returns the q value in the parameters of a media type in an Accept header, which is 1 by default
+func acceptQuality(params string) float64 {
+	for _, param := range strings.Split(params, ";") {
+		name, value, _ := strings.Cut(param, "=")
+		if strings.TrimSpace(name) != "q" {
+			continue
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || q < 0 {
+			return 0
+		}
+		return q
+	}
+	return 1
+}
+
+// register adds a client, returning the events to send it before any others,
+// which include the events in the history after its last event ID.
+//
+// Clients aren't added once the handler is shutting down.
+func (h *Handler) register(c *client) ([]sse.Event, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.drained != nil {
+		return nil, errShuttingDown
+	}
+
+	var missed []sse.Event
+	if h.history != nil && c.lastEventID != "" {
+		var err error
+		if missed, err = h.history.since(c.lastEventID); err != nil {
+			return nil, err
+		}
+	}
+
+	initial := make([]sse.Event, 0, len(h.lastValues)+len(h.replay))
+	types := make([]string, 0, len(h.lastValues))
+	for t := range h.lastValues {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		initial = append(initial, h.lastValues[t])
+	}
+	initial = append(initial, h.replayFor(c.lastEventID)...)
+	initial = append(initial, missed...)
+
+	h.clients[c] = struct{}{}
+	addToIndex(h.ids, c.id, c)
+	if h.stickyRouting != nil {
+		addToIndex(h.groups, c.group, c)
+	}
+	h.metrics.connected()
+	return initial, nil
+}
+
+// unregister removes a client if it hasn't been already
+func (h *Handler) unregister(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(c)
+}
+
+// remove removes a client with the lock held, reporting false if it was already removed
+func (h *Handler) remove(c *client) bool {
+	if _, ok := h.clients[c]; !ok {
+		return false
+	}
+
+	delete(h.clients, c)
+	removeFromIndex(h.ids, c.id, c)
+	if h.stickyRouting != nil {
+		removeFromIndex(h.groups, c.group, c)
+	}
+	h.metrics.disconnected()
+	if h.drained != nil && len(h.clients) == 0 {
+		close(h.drained)
+	}
+	return true
+}
+
+// ClientInfo describes a connected client
+type ClientInfo struct {
+	ID    string `json:"id"`
+	Group string `json:"group,omitempty"`
+}
+
+// Clients returns the connected clients, sorted by ID
+func (h *Handler) Clients() []ClientInfo {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	clients := make([]ClientInfo, 0, len(h.clients))
+	for c := range h.clients {
+		clients = append(clients, ClientInfo{ID: c.id, Group: c.group})
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		if clients[i].ID != clients[j].ID {
+			return clients[i].ID < clients[j].ID
+		}
+		return clients[i].Group < clients[j].Group
+	})
+	return clients
+}
+
+// Disconnect ends the connections of the clients with an ID, reporting false if none are connected
+func (h *Handler) Disconnect(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	clients := h.ids[id]
+	if len(clients) == 0 {
+		return false
+	}
+	for c := range clients {
+		if h.remove(c) {
+			close(c.disconnect)
+		}
+	}
+	return true
+}
+
+func addToIndex(index map[string]map[*client]struct{}, key string, c *client) {
+	if index[key] == nil {
+		index[key] = make(map[*client]struct{})
+	}
+	index[key][c] = struct{}{}
+}
+
+func removeFromIndex(index map[string]map[*client]struct{}, key string, c *client) {
+	delete(index[key], c)
+	if len(index[key]) == 0 {
+		delete(index, key)
+	}
+}
+
+// eventType returns the type of an event, which is message if it's empty
+func eventType(event sse.Event) string {
+	if event.Type == "" {
+		return "message"
+	}
+	return event.Type
+}
+
+// sendAll sends a batch of events to clients, returning the ones it was dropped for
+func sendAll(clients map[*client]struct{}, events ...sse.Event) []*client {
+	var dropped []*client
+	for c := range clients {
+		if !c.send(events) {
+			dropped = append(dropped, c)
+		}
+	}
+	return dropped
+}
+
+// send queues a batch of events for the client, reporting false if its buffer is full
+func (c *client) send(events []sse.Event) bool {
+	select {
+	case c.events <- events:
+		return true
+	default:
+		return false
+	}
+}
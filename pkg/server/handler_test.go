@@ -0,0 +1,538 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerSend(t *testing.T) {
+	h := NewHandler()
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	a, err := sse.New(server.URL)
+	require.NoError(t, err)
+	b, err := sse.New(server.URL)
+	require.NoError(t, err)
+
+	event := sse.Event{Type: "score", Data: "multi\nline"}
+	h.Send(event)
+
+	assert.Equal(t, event, <-a.Events())
+	assert.Equal(t, event, <-b.Events())
+}
+
+func TestHandlerPublishVersioned(t *testing.T) {
+	h := NewHandler()
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	s, err := sse.New(server.URL, sse.WithMinVersion(2))
+	require.NoError(t, err)
+
+	h.PublishVersioned(sse.Event{Type: "score", Data: "old"}, 1)
+	h.PublishVersioned(sse.Event{Type: "score", Data: "new"}, 2)
+
+	assert.Equal(t, sse.Event{Type: "score", Data: "new", Version: 2}, <-s.Events())
+
+	b, err := FormatNDJSON.marshal(sse.Event{Type: "score", Data: "new", Version: 2}, LF)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"type\":\"score\",\"data\":\"new\",\"version\":2}\n", string(b))
+}
+
+func TestHandlerSendToGroup(t *testing.T) {
+	h := NewHandler(WithStickyRouting(func(r *http.Request) string {
+		return r.URL.Query().Get("user")
+	}))
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	alice, err := sse.New(server.URL + "?user=alice")
+	require.NoError(t, err)
+	bob, err := sse.New(server.URL + "?user=bob")
+	require.NoError(t, err)
+
+	h.SendToGroup("alice", sse.Event{Type: "message", Data: "for alice"})
+	h.SendToGroup("bob", sse.Event{Type: "message", Data: "for bob"})
+
+	assert.Equal(t, "for alice", (<-alice.Events()).Data)
+	assert.Equal(t, "for bob", (<-bob.Events()).Data)
+}
+
+func TestHandlerWithEncodings(t *testing.T) {
+	h := NewHandler(WithEncodings(FormatSSE, FormatJSONStream, FormatNDJSON))
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	type testCase struct {
+		accept      string
+		contentType string
+		body        string
+	}
+	testCases := []testCase{
+		{"", "text/event-stream", "event: score\ndata: 1\n\n"},
+		{"text/event-stream", "text/event-stream", "event: score\ndata: 1\n\n"},
+		{"application/stream+json", "application/stream+json", "data: {\"type\":\"score\",\"data\":\"1\"}\n\n"},
+		{"application/x-ndjson;q=0.9, text/event-stream", "text/event-stream", "event: score\ndata: 1\n\n"},
+		{"text/event-stream;q=0.5, application/x-ndjson", "application/x-ndjson", "{\"type\":\"score\",\"data\":\"1\"}\n"},
+		{"application/stream+json;q=0, application/x-ndjson;q=0.1", "application/x-ndjson", "{\"type\":\"score\",\"data\":\"1\"}\n"},
+		{"application/xml", "text/event-stream", "event: score\ndata: 1\n\n"},
+	}
+
+	for _, tc := range testCases {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", tc.accept)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		assert.Equal(t, tc.contentType, resp.Header.Get("Content-Type"))
+
+		h.Send(sse.Event{Type: "score", Data: "1"})
+		body := make([]byte, len(tc.body))
+		_, err = io.ReadFull(resp.Body, body)
+		require.NoError(t, err)
+		assert.Equal(t, tc.body, string(body))
+		resp.Body.Close()
+	}
+}
+
+func TestHandlerWithEncodingsEmpty(t *testing.T) {
+	h := NewHandler(WithEncodings())
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	assert.Equal(t, FormatSSE, h.negotiate(req))
+}
+
+func TestHandlerWithLineEnding(t *testing.T) {
+	type testCase struct {
+		lineEnding LineEnding
+		body       string
+	}
+	testCases := []testCase{
+		{LF, "event: score\ndata: 1\ndata: 2\n\n"},
+		{CRLF, "event: score\r\ndata: 1\r\ndata: 2\r\n\r\n"},
+		{CR, "event: score\rdata: 1\rdata: 2\r\r"},
+	}
+
+	for _, tc := range testCases {
+		h := NewHandler(WithLineEnding(tc.lineEnding))
+		server := httptest.NewServer(h)
+
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+
+		event := sse.Event{Type: "score", Data: "1\n2"}
+		h.Send(event)
+		body := make([]byte, len(tc.body))
+		_, err = io.ReadFull(resp.Body, body)
+		require.NoError(t, err)
+		assert.Equal(t, tc.body, string(body))
+
+		var buf bytes.Buffer
+		w := NewEventWriter(&buf)
+		w.SetLineEnding(tc.lineEnding)
+		require.NoError(t, w.WriteEvent(event))
+		assert.Equal(t, tc.body, buf.String())
+
+		resp.Body.Close()
+		server.CloseClientConnections()
+		server.Close()
+	}
+}
+
+func TestWriteEvent(t *testing.T) {
+	events := []sse.Event{
+		{Type: "message", Data: "plain"},
+		{Type: "score", Data: "1\n2", ID: "7"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, WriteComment(w, "heartbeat\nping"))
+		for _, event := range events {
+			assert.NoError(t, WriteEvent(w, event))
+		}
+	}))
+	defer server.Close()
+
+	s, err := sse.New(server.URL, sse.WithMaxReconnectAttempts(0))
+	require.NoError(t, err)
+	var received []sse.Event
+	for event := range s.Events() {
+		received = append(received, event)
+	}
+	assert.Equal(t, events, received)
+
+	w := httptest.NewRecorder()
+	require.NoError(t, WriteEvent(w, events[0]))
+	assert.Equal(t, "data: plain\n\n", w.Body.String())
+	assert.True(t, w.Flushed)
+
+	w = httptest.NewRecorder()
+	require.NoError(t, WriteComment(w, "heartbeat\nping"))
+	assert.Equal(t, ": heartbeat\n: ping\n", w.Body.String())
+	assert.True(t, w.Flushed)
+}
+
+func TestWriteEventInjection(t *testing.T) {
+	type testCase struct {
+		event sse.Event
+		body  string
+	}
+	testCases := []testCase{
+		{sse.Event{Data: "a\rdata: b\r\nc"}, "data: a\ndata: data: b\ndata: c\n\n"},
+		{sse.Event{Data: "a\r\revent: admin\rdata: b"}, "data: a\ndata: \ndata: event: admin\ndata: data: b\n\n"},
+		{sse.Event{ID: "1\nevent: admin\r\ndata: x\n", Data: "a"}, "id: 1event: admindata: x\ndata: a\n\n"},
+		{sse.Event{Type: "score\r\rdata: x", Data: "a"}, "event: scoredata: x\ndata: a\n\n"},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		require.NoError(t, WriteEvent(w, tc.event))
+		assert.Equal(t, tc.body, w.Body.String())
+
+		events, err := sse.ParseAll(strings.NewReader(w.Body.String()))
+		require.NoError(t, err)
+		assert.Len(t, events, 1, "an event can't inject another")
+	}
+
+	w := httptest.NewRecorder()
+	require.NoError(t, WriteComment(w, "heartbeat\rdata: x"))
+	assert.Equal(t, ": heartbeat\n: data: x\n", w.Body.String())
+}
+
+func TestHandlerWithEventSerializer(t *testing.T) {
+	h := NewHandler(WithEventSerializer(func(event sse.Event) ([]byte, error) {
+		if event.Type == "skip" {
+			return nil, errors.New("skipped")
+		}
+		return []byte("event: envelope\ndata: " + event.Type + "=" + event.Data + "\n\n"), nil
+	}))
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	s, err := sse.New(server.URL)
+	require.NoError(t, err)
+
+	h.Send(sse.Event{Type: "skip", Data: "1"})
+	h.Send(sse.Event{Type: "score", Data: "2"})
+
+	assert.Equal(t, sse.Event{Type: "envelope", Data: "score=2"}, <-s.Events())
+}
+
+func TestHandlerWithEventRetryPolicy(t *testing.T) {
+	h := NewHandler(WithEventRetryPolicy(func(event sse.Event) bool {
+		return event.Type == "critical"
+	}))
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	h.Send(sse.Event{Type: "critical", Data: "1"})
+	h.Send(sse.Event{Type: "message", Data: "2"})
+
+	s, err := sse.New(server.URL)
+	require.NoError(t, err)
+
+	h.Send(sse.Event{Type: "critical", Data: "3"})
+
+	assert.Equal(t, sse.Event{Type: "critical", Data: "1"}, <-s.Events())
+	assert.Equal(t, sse.Event{Type: "critical", Data: "3"}, <-s.Events())
+
+	for i := 0; i < defaultReplayBufferSize; i++ {
+		h.Send(sse.Event{Type: "critical", Data: "4"})
+	}
+	assert.Len(t, h.replay, defaultReplayBufferSize)
+	assert.Equal(t, "4", h.replay[0].event.Data)
+}
+
+func TestHandlerWithEventRetryPolicyLastEventID(t *testing.T) {
+	h := NewHandler(WithAutoID(1), WithEventRetryPolicy(func(event sse.Event) bool {
+		return event.Type == "critical"
+	}))
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	h.Send(sse.Event{Type: "critical", Data: "1"})
+	h.Send(sse.Event{Type: "message", Data: "2"})
+	h.Send(sse.Event{Type: "critical", Data: "3"})
+	h.Send(sse.Event{Type: "message", Data: "4"})
+
+	type testCase struct {
+		lastEventID string
+		replayed    []string
+	}
+	testCases := []testCase{
+		{"", []string{"1", "3"}},
+		{"1", []string{"3"}},
+		{"2", []string{"3"}},
+		{"4", nil},
+		{"unknown", []string{"1", "3"}},
+	}
+	for _, tc := range testCases {
+		var replayed []string
+		for _, event := range h.replayFor(tc.lastEventID) {
+			replayed = append(replayed, event.Data)
+		}
+		assert.Equal(t, tc.replayed, replayed, tc.lastEventID)
+	}
+
+	s, err := sse.New(server.URL, sse.WithHeader("Last-Event-ID", "2"))
+	require.NoError(t, err)
+	h.Send(sse.Event{Type: "message", Data: "5"})
+	assert.Equal(t, sse.Event{Type: "critical", Data: "3", ID: "3"}, <-s.Events())
+	assert.Equal(t, sse.Event{Type: "message", Data: "5", ID: "5"}, <-s.Events())
+}
+
+func TestHandlerWithLastValueCache(t *testing.T) {
+	h := NewHandler(WithLastValueCache())
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	h.Send(sse.Event{Type: "price", Data: "1"})
+	h.Send(sse.Event{Type: "volume", Data: "10"})
+	h.Send(sse.Event{Type: "price", Data: "2"})
+	h.Send(sse.Event{Data: "hello"})
+
+	s, err := sse.New(server.URL)
+	require.NoError(t, err)
+
+	h.Send(sse.Event{Type: "price", Data: "3"})
+
+	assert.Equal(t, sse.Event{Type: "message", Data: "hello"}, <-s.Events())
+	assert.Equal(t, sse.Event{Type: "price", Data: "2"}, <-s.Events())
+	assert.Equal(t, sse.Event{Type: "volume", Data: "10"}, <-s.Events())
+	assert.Equal(t, sse.Event{Type: "price", Data: "3"}, <-s.Events())
+}
+
+func TestHandlerOnSendError(t *testing.T) {
+	h := NewHandler(WithEventSerializer(func(event sse.Event) ([]byte, error) {
+		if event.Type == "skip" {
+			return nil, errors.New("skipped")
+		}
+		return FormatSSE.marshal(event, LF)
+	}))
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	errs := make(chan error, 2)
+	h.OnSendError(func(clientID string, event sse.Event, err error) {
+		assert.Equal(t, "skip", event.Type)
+		errs <- err
+	})
+
+	s, err := sse.New(server.URL)
+	require.NoError(t, err)
+
+	h.Send(sse.Event{Type: "skip", Data: "1"})
+	h.Send(sse.Event{Type: "score", Data: "2"})
+	assert.Equal(t, "2", (<-s.Events()).Data)
+	assert.ErrorContains(t, <-errs, "skipped")
+
+	slow := &client{id: "slow", events: make(chan []sse.Event, 1)}
+	h.register(slow)
+	defer h.unregister(slow)
+
+	var dropped []string
+	h.OnSendError(func(clientID string, event sse.Event, err error) {
+		assert.ErrorIs(t, err, ErrClientBufferFull)
+		dropped = append(dropped, clientID+"="+event.Data)
+	})
+	h.SendToGroup("none", sse.Event{Data: "0"})
+	require.NoError(t, h.EmitTo("slow", sse.Event{Data: "1"}))
+	require.NoError(t, h.EmitTo("slow", sse.Event{Data: "2"}))
+	assert.Equal(t, []string{"slow=2"}, dropped)
+}
+
+func TestHandlerWithClientBufferSize(t *testing.T) {
+	h := NewHandler(WithClientBufferSize(64))
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	_, err := sse.New(server.URL)
+	require.NoError(t, err)
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	require.Len(t, h.clients, 1)
+	for c := range h.clients {
+		assert.Equal(t, 64, cap(c.events))
+	}
+}
+
+func TestHandlerWithClientBufferSizeNegative(t *testing.T) {
+	assert.PanicsWithError(t, "invalid client buffer size -1", func() {
+		NewHandler(WithClientBufferSize(-1))
+	})
+}
+
+// blockingWriter is a response writer whose writes block until unblock is closed
+type blockingWriter struct {
+	*httptest.ResponseRecorder
+	unblock chan struct{}
+}
+
+func (w *blockingWriter) Write(b []byte) (int, error) {
+	<-w.unblock
+	return w.ResponseRecorder.Write(b)
+}
+
+func TestHandlerUnregistersOnCancel(t *testing.T) {
+	h := NewHandler(WithClientID(func(r *http.Request) string { return "a" }))
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	w := &blockingWriter{httptest.NewRecorder(), make(chan struct{})}
+
+	served := make(chan struct{})
+	go func() {
+		h.ServeHTTP(w, r)
+		close(served)
+	}()
+	require.Eventually(t, func() bool { return h.EmitTo("a", sse.Event{Data: "1"}) == nil }, time.Second, time.Millisecond)
+
+	cancel()
+	assert.Eventually(t, func() bool { return errors.Is(h.EmitTo("a", sse.Event{Data: "2"}), ErrUnknownClient) }, time.Second, time.Millisecond)
+
+	close(w.unblock)
+	<-served
+}
+
+// flushRecorder is a response writer recording the body written by each flush
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	mu      sync.Mutex
+	pending bytes.Buffer
+	flushes []string
+}
+
+func (w *flushRecorder) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.pending.Write(b)
+}
+
+func (w *flushRecorder) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flushes = append(w.flushes, w.pending.String())
+	w.pending.Reset()
+}
+
+func (w *flushRecorder) flushed() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.flushes...)
+}
+
+func TestHandlerSendBatch(t *testing.T) {
+	h := NewHandler()
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	w := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+
+	served := make(chan struct{})
+	go func() {
+		h.ServeHTTP(w, r)
+		close(served)
+	}()
+	require.Eventually(t, func() bool { return len(h.Clients()) == 1 }, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return len(w.flushed()) == 1 }, time.Second, time.Millisecond)
+
+	require.NoError(t, h.SendBatch([]sse.Event{{Data: "1"}, {Data: "2"}, {Data: "3"}}))
+	require.Eventually(t, func() bool { return len(w.flushed()) == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, "data: 1\n\ndata: 2\n\ndata: 3\n\n", w.flushed()[1])
+	assert.Equal(t, uint64(3), h.Stats().EventsSent)
+
+	cancel()
+	<-served
+}
+
+func TestHandlerSendBatchDropped(t *testing.T) {
+	h := NewHandler()
+	slow := &client{id: "slow", events: make(chan []sse.Event, 1)}
+	h.register(slow)
+	defer h.unregister(slow)
+
+	var dropped []string
+	h.OnSendError(func(clientID string, event sse.Event, err error) {
+		assert.ErrorIs(t, err, ErrClientBufferFull)
+		dropped = append(dropped, clientID+"="+event.Data)
+	})
+	require.NoError(t, h.SendBatch([]sse.Event{{Data: "1"}, {Data: "2"}}))
+	require.NoError(t, h.SendBatch([]sse.Event{{Data: "3"}, {Data: "4"}}))
+	assert.Equal(t, []sse.Event{{Data: "1"}, {Data: "2"}}, <-slow.events)
+	assert.Equal(t, []string{"slow=3", "slow=4"}, dropped)
+	assert.Equal(t, uint64(2), h.Stats().EventsDropped)
+}
+
+func TestHandlerConcurrentSend(t *testing.T) {
+	const publishers = 100
+
+	h := NewHandler(
+		WithClientID(func(r *http.Request) string { return "a" }),
+		WithStickyRouting(func(r *http.Request) string { return "group" }),
+		WithEventRetryPolicy(func(event sse.Event) bool { return event.Type == "critical" }),
+		WithLastValueCache(),
+		WithAutoID(1),
+	)
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	var dropped atomic.Int64
+	h.OnSendError(func(clientID string, event sse.Event, err error) {
+		dropped.Add(1)
+	})
+
+	s, err := sse.New(server.URL)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < publishers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			event := sse.Event{Type: "critical", Data: strconv.Itoa(i)}
+			switch i % 3 {
+			case 0:
+				h.Send(event)
+			case 1:
+				h.SendToGroup("group", event)
+			case 2:
+				assert.NoError(t, h.EmitTo("a", event))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	received := 0
+	for int64(received)+dropped.Load() < publishers {
+		select {
+		case <-s.Events():
+			received++
+		case <-time.After(time.Second):
+			t.Fatalf("received %d events and dropped %d, expected %d", received, dropped.Load(), publishers)
+		}
+	}
+}
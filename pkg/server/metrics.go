@@ -0,0 +1,123 @@
+package server
+
+import (
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// HandlerStats is a snapshot of what a Handler has done
+type HandlerStats struct {
+	// ConnectedClients is the number of clients connected
+	ConnectedClients int `json:"connected_clients"`
+	// EventsSent is the number of events written to clients
+	EventsSent uint64 `json:"events_sent"`
+	// EventsDropped is the number of events dropped for clients that weren't keeping up
+	EventsDropped uint64 `json:"events_dropped"`
+	// Errors is the number of events that couldn't be serialized or written to a client
+	Errors uint64 `json:"errors"`
+}
+
+// metrics counts what a handler does, updating its Prometheus metrics too if they're registered
+type metrics struct {
+	eventsSent    atomic.Uint64
+	eventsDropped atomic.Uint64
+	errors        atomic.Uint64
+
+	prometheus *prometheusMetrics
+}
+
+type prometheusMetrics struct {
+	connectedClients prometheus.Gauge
+	eventsSent       prometheus.Counter
+	eventsDropped    prometheus.Counter
+	errors           prometheus.Counter
+}
+
+// WithPrometheusMetrics registers the handler's metrics with registerer under the sse_server_ prefix.
+//
+// The metrics are registered when the option is applied, which panics if they're already registered.
+func WithPrometheusMetrics(registerer prometheus.Registerer) Option {
+	return func(h *Handler) {
+		factory := promauto.With(registerer)
+		h.metrics.prometheus = &prometheusMetrics{
+			connectedClients: factory.NewGauge(prometheus.GaugeOpts{
+				Namespace: "sse",
+				Subsystem: "server",
+				Name:      "connected_clients",
+				Help:      "Number of clients connected to the handler.",
+			}),
+			eventsSent: factory.NewCounter(prometheus.CounterOpts{
+				Namespace: "sse",
+				Subsystem: "server",
+				Name:      "events_sent_total",
+				Help:      "Number of events written to clients.",
+			}),
+			eventsDropped: factory.NewCounter(prometheus.CounterOpts{
+				Namespace: "sse",
+				Subsystem: "server",
+				Name:      "events_dropped_total",
+				Help:      "Number of events dropped for clients that weren't keeping up.",
+			}),
+			errors: factory.NewCounter(prometheus.CounterOpts{
+				Namespace: "sse",
+				Subsystem: "server",
+				Name:      "errors_total",
+				Help:      "Number of events that couldn't be serialized or written to a client.",
+			}),
+		}
+	}
+}
+
+// NewInstrumentedHandler constructs a Handler with its metrics registered with registerer
+func NewInstrumentedHandler(registerer prometheus.Registerer, opts ...Option) *Handler {
+	return NewHandler(append([]Option{WithPrometheusMetrics(registerer)}, opts...)...)
+}
+
+// Stats returns a snapshot of the handler's statistics
+func (h *Handler) Stats() HandlerStats {
+	h.mu.RLock()
+	connected := len(h.clients)
+	h.mu.RUnlock()
+
+	return HandlerStats{
+		ConnectedClients: connected,
+		EventsSent:       h.metrics.eventsSent.Load(),
+		EventsDropped:    h.metrics.eventsDropped.Load(),
+		Errors:           h.metrics.errors.Load(),
+	}
+}
+
+func (m *metrics) connected() {
+	if m.prometheus != nil {
+		m.prometheus.connectedClients.Inc()
+	}
+}
+
+func (m *metrics) disconnected() {
+	if m.prometheus != nil {
+		m.prometheus.connectedClients.Dec()
+	}
+}
+
+func (m *metrics) sent() {
+	m.eventsSent.Add(1)
+	if m.prometheus != nil {
+		m.prometheus.eventsSent.Inc()
+	}
+}
+
+func (m *metrics) dropped(n int) {
+	m.eventsDropped.Add(uint64(n))
+	if m.prometheus != nil {
+		m.prometheus.eventsDropped.Add(float64(n))
+	}
+}
+
+func (m *metrics) failed() {
+	m.errors.Add(1)
+	if m.prometheus != nil {
+		m.prometheus.errors.Inc()
+	}
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPrometheusMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	h := NewInstrumentedHandler(registry, WithEventSerializer(func(event sse.Event) ([]byte, error) {
+		if event.Type == "broken" {
+			return nil, errors.New("broken")
+		}
+		return []byte("data: " + event.Data + "\n\n"), nil
+	}))
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	s, err := sse.New(server.URL, sse.WithMaxReconnectAttempts(0))
+	require.NoError(t, err)
+
+	h.Send(sse.Event{Type: "broken"})
+	h.Send(sse.Event{Data: "ok"})
+	assert.Equal(t, "ok", (<-s.Events()).Data)
+	h.reportDropped([]*client{{id: "slow"}}, sse.Event{})
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(h.metrics.prometheus.connectedClients))
+	assert.Equal(t, 1.0, testutil.ToFloat64(h.metrics.prometheus.eventsSent))
+	assert.Equal(t, 1.0, testutil.ToFloat64(h.metrics.prometheus.eventsDropped))
+	assert.Equal(t, 1.0, testutil.ToFloat64(h.metrics.prometheus.errors))
+
+	server.CloseClientConnections()
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(h.metrics.prometheus.connectedClients) == 0
+	}, time.Second, time.Millisecond)
+
+	count, err := testutil.GatherAndCount(registry)
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+}
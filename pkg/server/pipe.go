@@ -0,0 +1,51 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// writerHandler writes the events posted to it to an io.Writer
+type writerHandler struct {
+	mu     sync.Mutex
+	writer *EventWriter
+}
+
+// NewWriterHandler constructs an http.Handler that writes events to w in the event stream format instead of streaming them to clients,
+// so they can be piped to files, stdout or other processes.
+//
+// Events are posted as newline delimited JSON objects with id, type and data fields, the encoding of FormatNDJSON.
+// Events posted concurrently are written one at a time.
+func NewWriterHandler(w io.Writer) http.Handler {
+	return &writerHandler{writer: NewEventWriter(w)}
+}
+
+// ServeHTTP writes the events in the request body, responding with 204 No Content once they're written
+func (h *writerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	for {
+		var event jsonEvent
+		if err := decoder.Decode(&event); err == io.EOF {
+			break
+		} else if err != nil {
+			http.Error(w, "invalid event: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		h.mu.Lock()
+		err := h.writer.WriteEvent(event.event())
+		h.mu.Unlock()
+		if err != nil {
+			http.Error(w, "writing event: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
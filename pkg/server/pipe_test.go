@@ -0,0 +1,30 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriterHandler(t *testing.T) {
+	var out bytes.Buffer
+	h := NewWriterHandler(&out)
+
+	w := httptest.NewRecorder()
+	body := `{"id":"1","type":"score","data":"1"}` + "\n" + `{"type":"message","data":"multi\nline"}` + "\n"
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "id: 1\nevent: score\ndata: 1\n\ndata: multi\ndata: line\n\n", out.String())
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/pkg/errors"
+)
+
+// ErrRateLimited is returned by RateLimitMiddleware when an event exceeds the rate limit
+var ErrRateLimited = errors.New("rate limited")
+
+// EventHandler processes an event
+type EventHandler func(sse.Event) error
+
+// EventMiddleware wraps an EventHandler, like http.Handler middleware
+type EventMiddleware func(next EventHandler) EventHandler
+
+// Pipeline runs events through a chain of middleware before publishing them to a Broker
+type Pipeline struct {
+	broker      *Broker
+	middlewares []EventMiddleware
+}
+
+// NewPipeline constructs a Pipeline publishing to broker
+//
+// Middleware is run in the order given, the first middleware sees the event first.
+func NewPipeline(broker *Broker, middlewares ...EventMiddleware) *Pipeline {
+	return &Pipeline{broker: broker, middlewares: middlewares}
+}
+
+// Publish runs event through the middleware and publishes it to topic if none of them return an error
+func (p *Pipeline) Publish(topic string, event sse.Event) error {
+	var handler EventHandler = func(event sse.Event) error {
+		p.broker.Topic(topic).Publish(event)
+		return nil
+	}
+	for i := len(p.middlewares) - 1; i >= 0; i-- {
+		handler = p.middlewares[i](handler)
+	}
+	return handler(event)
+}
+
+// LoggingMiddleware logs every event and any error processing it to logger
+func LoggingMiddleware(logger *log.Logger) EventMiddleware {
+	return func(next EventHandler) EventHandler {
+		return func(event sse.Event) error {
+			err := next(event)
+			if err != nil {
+				logger.Printf("sse: publishing %s event (%d bytes): %v", event.Type, len(event.Data), err)
+			} else {
+				logger.Printf("sse: published %s event (%d bytes)", event.Type, len(event.Data))
+			}
+			return err
+		}
+	}
+}
+
+// RateLimitMiddleware rejects events with ErrRateLimited once more than burst events have been published
+// faster than rate events per second
+func RateLimitMiddleware(rate float64, burst int) EventMiddleware {
+	var (
+		mu     sync.Mutex
+		tokens = float64(burst)
+		last   = time.Now()
+	)
+
+	return func(next EventHandler) EventHandler {
+		return func(event sse.Event) error {
+			mu.Lock()
+			now := time.Now()
+			tokens += now.Sub(last).Seconds() * rate
+			if tokens > float64(burst) {
+				tokens = float64(burst)
+			}
+			last = now
+			allowed := tokens >= 1
+			if allowed {
+				tokens--
+			}
+			mu.Unlock()
+
+			if !allowed {
+				return ErrRateLimited
+			}
+			return next(event)
+		}
+	}
+}
+
+// SchemaValidationMiddleware rejects events validate returns an error for
+func SchemaValidationMiddleware(validate func(sse.Event) error) EventMiddleware {
+	return func(next EventHandler) EventHandler {
+		return func(event sse.Event) error {
+			if err := validate(event); err != nil {
+				return errors.Wrap(err, "invalid event")
+			}
+			return next(event)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"testing"
+	"time"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipeline(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var order []string
+	trace := func(name string) EventMiddleware {
+		return func(next EventHandler) EventHandler {
+			return func(event sse.Event) error {
+				order = append(order, name)
+				return next(event)
+			}
+		}
+	}
+
+	var logs bytes.Buffer
+	broker := NewBroker()
+	scores := broker.Topic("scores").Subscribe()
+	p := NewPipeline(broker,
+		trace("first"),
+		trace("second"),
+		LoggingMiddleware(log.New(&logs, "", 0)),
+		SchemaValidationMiddleware(func(event sse.Event) error {
+			if !json.Valid([]byte(event.Data)) {
+				return errors.New("data isn't json")
+			}
+			return nil
+		}),
+	)
+
+	require.NoError(p.Publish("scores", sse.Event{Type: "score", Data: `{"score": 1}`}))
+	assert.Equal(sse.Event{Type: "score", Data: `{"score": 1}`}, <-scores)
+	assert.Equal([]string{"first", "second"}, order)
+
+	assert.Error(p.Publish("scores", sse.Event{Type: "score", Data: "not json"}))
+	assert.Empty(scores)
+
+	assert.Equal("sse: published score event (12 bytes)\nsse: publishing score event (8 bytes): invalid event: data isn't json\n", logs.String())
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	p := NewPipeline(NewBroker(), RateLimitMiddleware(100, 2))
+
+	assert.NoError(t, p.Publish("t", sse.Event{}))
+	assert.NoError(t, p.Publish("t", sse.Event{}))
+	assert.Equal(t, ErrRateLimited, p.Publish("t", sse.Event{}))
+
+	time.Sleep(20 * time.Millisecond)
+	assert.NoError(t, p.Publish("t", sse.Event{}))
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	"github.com/pkg/errors"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS events (
+	seq INTEGER PRIMARY KEY AUTOINCREMENT,
+	id TEXT NOT NULL,
+	type TEXT NOT NULL,
+	data TEXT NOT NULL,
+	ts INTEGER NOT NULL,
+	version INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS events_id ON events (id);
+CREATE INDEX IF NOT EXISTS events_ts ON events (ts);
+`
+
+// NewSQLiteHandler constructs a Handler that stores every event passed to Handler.Send in the events table of a SQLite database,
+// creating the table if it doesn't exist.
+//
+// Clients reconnecting with a Last-Event-ID header are sent the events stored after it before any others.
+// Clients whose last event ID isn't stored, for example because it was pruned, are sent every stored event.
+// Events without an ID are stored with their sequence number as their ID, so clients can resume from them.
+// The database/sql driver isn't imported by this package.
+func NewSQLiteHandler(db *sql.DB, opts ...Option) (*Handler, error) {
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return nil, errors.Wrap(err, "creating events table")
+	}
+	if err := addVersionColumn(db); err != nil {
+		return nil, errors.Wrap(err, "adding version column")
+	}
+
+	h := NewHandler(opts...)
+	h.history = &sqliteHistory{db: db}
+	return h, nil
+}
+
+// PruneHistory deletes the events stored before a time
+func (h *Handler) PruneHistory(before time.Time) error {
+	if h.history == nil {
+		return errors.New("no event history")
+	}
+	_, err := h.history.db.Exec(`DELETE FROM events WHERE ts < ?`, before.UnixNano())
+	return errors.Wrap(err, "pruning events")
+}
+
+// addVersionColumn adds the version column to events tables created before it was
+func addVersionColumn(db *sql.DB) error {
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('events') WHERE name = 'version'`).Scan(&n); err != nil {
+		return err
+	}
+	if n != 0 {
+		return nil
+	}
+	_, err := db.Exec(`ALTER TABLE events ADD COLUMN version INTEGER NOT NULL DEFAULT 0`)
+	return err
+}
+
+// sqliteHistory stores events in a SQLite database
+type sqliteHistory struct {
+	db *sql.DB
+}
+
+// append stores an event, returning it with its sequence number as its ID if it doesn't have one
+func (s *sqliteHistory) append(event sse.Event) (sse.Event, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return event, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`INSERT INTO events (id, type, data, ts, version) VALUES (?, ?, ?, ?, ?)`,
+		event.ID, event.Type, event.Data, time.Now().UnixNano(), event.Version)
+	if err != nil {
+		return event, err
+	}
+	if event.ID == "" {
+		seq, err := result.LastInsertId()
+		if err != nil {
+			return event, err
+		}
+		id := strconv.FormatInt(seq, 10)
+		if _, err := tx.Exec(`UPDATE events SET id = ? WHERE seq = ?`, id, seq); err != nil {
+			return event, err
+		}
+		event.ID = id
+	}
+
+	return event, tx.Commit()
+}
+
+// since returns the events stored after the latest event with an ID, oldest first.
+// Every stored event is returned if there isn't an event with the ID, for example because it was pruned.
+func (s *sqliteHistory) since(lastEventID string) ([]sse.Event, error) {
+	rows, err := s.db.Query(`
+		SELECT id, type, data, version FROM events
+		WHERE seq > COALESCE((SELECT MAX(seq) FROM events WHERE id = ?), 0)
+		ORDER BY seq`, lastEventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []sse.Event
+	for rows.Next() {
+		var event sse.Event
+		if err := rows.Scan(&event.ID, &event.Type, &event.Data, &event.Version); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
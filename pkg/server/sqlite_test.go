@@ -0,0 +1,83 @@
+package server
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteHandler(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "events.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	h, err := NewSQLiteHandler(db)
+	require.NoError(t, err)
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	h.Send(sse.Event{Type: "score", Data: "1"})
+	h.Send(sse.Event{Type: "score", Data: "2", ID: "custom"})
+	h.Send(sse.Event{Type: "score", Data: "3"})
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	h.Send(sse.Event{Type: "score", Data: "4"})
+
+	expected := "id: custom\nevent: score\ndata: 2\n\nid: 3\nevent: score\ndata: 3\n\nid: 4\nevent: score\ndata: 4\n\n"
+	body := make([]byte, len(expected))
+	_, err = io.ReadFull(resp.Body, body)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(body))
+
+	missed, err := h.history.since("unknown")
+	require.NoError(t, err)
+	assert.Len(t, missed, 4, "clients with an unknown last event ID are sent every stored event")
+
+	require.NoError(t, h.PruneHistory(time.Now()))
+	h.PublishVersioned(sse.Event{Type: "score", Data: "5"}, 2)
+	missed, err = h.history.since("1")
+	require.NoError(t, err)
+	assert.Equal(t, []sse.Event{{ID: "5", Type: "score", Data: "5", Version: 2}}, missed,
+		"clients whose last event ID was pruned are sent the oldest stored events")
+
+	assert.Error(t, NewHandler().PruneHistory(time.Now()))
+}
+
+func TestSQLiteHandlerAddsVersionColumn(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "events.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE events (
+		seq INTEGER PRIMARY KEY AUTOINCREMENT, id TEXT NOT NULL, type TEXT NOT NULL, data TEXT NOT NULL, ts INTEGER NOT NULL
+	)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO events (id, type, data, ts) VALUES ('1', 'score', 'old', 0)`)
+	require.NoError(t, err)
+
+	h, err := NewSQLiteHandler(db)
+	require.NoError(t, err)
+	_, err = NewSQLiteHandler(db)
+	require.NoError(t, err, "the column is only added once")
+
+	h.PublishVersioned(sse.Event{Type: "score", Data: "new"}, 3)
+	events, err := h.history.since("")
+	require.NoError(t, err)
+	assert.Equal(t, []sse.Event{{ID: "1", Type: "score", Data: "old"}, {ID: "2", Type: "score", Data: "new", Version: 3}}, events)
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// SubscriptionStore persists the topics each client is subscribed to
+type SubscriptionStore interface {
+	// Load returns the topics of every client
+	Load() (map[string][]string, error)
+	// Save replaces the topics of a client, removing the client if topics is empty
+	Save(clientID string, topics []string) error
+}
+
+// SubscriptionManager tracks which topics clients are subscribed to
+type SubscriptionManager struct {
+	mu          sync.RWMutex
+	clients     map[string]map[string]struct{}
+	subscribers map[string]map[string]struct{}
+
+	store SubscriptionStore
+}
+
+// SubscriptionOption configures a SubscriptionManager
+type SubscriptionOption func(*SubscriptionManager)
+
+// WithPersistence saves every change to store.
+// Call SubscriptionManager.Restore to load the saved subscriptions after a restart.
+func WithPersistence(store SubscriptionStore) SubscriptionOption {
+	return func(m *SubscriptionManager) {
+		m.store = store
+	}
+}
+
+// NewSubscriptionManager constructs a SubscriptionManager
+func NewSubscriptionManager(opts ...SubscriptionOption) *SubscriptionManager {
+	m := &SubscriptionManager{
+		clients:     make(map[string]map[string]struct{}),
+		subscribers: make(map[string]map[string]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Restore adds the subscriptions saved in the store
+func (m *SubscriptionManager) Restore() error {
+	if m.store == nil {
+		return errors.New("no subscription store")
+	}
+
+	saved, err := m.store.Load()
+	if err != nil {
+		return errors.Wrap(err, "loading subscriptions")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for clientID, topics := range saved {
+		m.add(clientID, topics)
+	}
+	return nil
+}
+
+// Subscribe adds topics to the subscriptions of a client
+//
+// The subscriptions are unchanged if saving them fails.
+func (m *SubscriptionManager) Subscribe(clientID string, topics []string) error {
+	if clientID == "" {
+		return errors.New("empty client id")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	subscribed := make(map[string]struct{}, len(m.clients[clientID])+len(topics))
+	for topic := range m.clients[clientID] {
+		subscribed[topic] = struct{}{}
+	}
+	for _, topic := range topics {
+		subscribed[topic] = struct{}{}
+	}
+	if err := m.save(clientID, subscribed); err != nil {
+		return err
+	}
+
+	m.add(clientID, topics)
+	return nil
+}
+
+// Unsubscribe removes topics from the subscriptions of a client
+//
+// The subscriptions are unchanged if saving them fails.
+func (m *SubscriptionManager) Unsubscribe(clientID string, topics []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	subscribed := make(map[string]struct{}, len(m.clients[clientID]))
+	for topic := range m.clients[clientID] {
+		subscribed[topic] = struct{}{}
+	}
+	for _, topic := range topics {
+		delete(subscribed, topic)
+	}
+	if err := m.save(clientID, subscribed); err != nil {
+		return err
+	}
+
+	for _, topic := range topics {
+		delete(m.clients[clientID], topic)
+		delete(m.subscribers[topic], clientID)
+		if len(m.subscribers[topic]) == 0 {
+			delete(m.subscribers, topic)
+		}
+	}
+	if len(m.clients[clientID]) == 0 {
+		delete(m.clients, clientID)
+	}
+	return nil
+}
+
+// ListSubscriptions returns the topics a client is subscribed to, sorted
+func (m *SubscriptionManager) ListSubscriptions(clientID string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return sortedKeys(m.clients[clientID])
+}
+
+// ListSubscribers returns the clients subscribed to a topic, sorted
+func (m *SubscriptionManager) ListSubscribers(topic string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return sortedKeys(m.subscribers[topic])
+}
+
+func (m *SubscriptionManager) add(clientID string, topics []string) {
+	for _, topic := range topics {
+		if m.clients[clientID] == nil {
+			m.clients[clientID] = make(map[string]struct{})
+		}
+		m.clients[clientID][topic] = struct{}{}
+
+		if m.subscribers[topic] == nil {
+			m.subscribers[topic] = make(map[string]struct{})
+		}
+		m.subscribers[topic][clientID] = struct{}{}
+	}
+}
+
+// save saves topics as the subscriptions of a client
+func (m *SubscriptionManager) save(clientID string, topics map[string]struct{}) error {
+	if m.store == nil {
+		return nil
+	}
+	return errors.Wrap(m.store.Save(clientID, sortedKeys(topics)), "saving subscriptions")
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memorySubscriptionStore map[string][]string
+
+func (m memorySubscriptionStore) Load() (map[string][]string, error) {
+	return m, nil
+}
+
+func (m memorySubscriptionStore) Save(clientID string, topics []string) error {
+	if len(topics) == 0 {
+		delete(m, clientID)
+		return nil
+	}
+	m[clientID] = topics
+	return nil
+}
+
+type failingSubscriptionStore struct {
+	memorySubscriptionStore
+	err error
+}
+
+func (f *failingSubscriptionStore) Save(clientID string, topics []string) error {
+	if f.err != nil {
+		return f.err
+	}
+	return f.memorySubscriptionStore.Save(clientID, topics)
+}
+
+func TestSubscriptionManager(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	store := make(memorySubscriptionStore)
+	m := NewSubscriptionManager(WithPersistence(store))
+
+	require.NoError(m.Subscribe("alice", []string{"scores", "news"}))
+	require.NoError(m.Subscribe("bob", []string{"scores"}))
+	assert.Error(m.Subscribe("", []string{"scores"}))
+
+	assert.Equal([]string{"news", "scores"}, m.ListSubscriptions("alice"))
+	assert.Equal([]string{"alice", "bob"}, m.ListSubscribers("scores"))
+
+	require.NoError(m.Unsubscribe("alice", []string{"scores"}))
+	require.NoError(m.Unsubscribe("bob", []string{"scores"}))
+	assert.Equal([]string{"news"}, m.ListSubscriptions("alice"))
+	assert.Empty(m.ListSubscribers("scores"))
+	assert.Empty(m.ListSubscriptions("bob"))
+	assert.Equal(memorySubscriptionStore{"alice": {"news"}}, store)
+
+	restored := NewSubscriptionManager(WithPersistence(store))
+	require.NoError(restored.Restore())
+	assert.Equal([]string{"alice"}, restored.ListSubscribers("news"))
+
+	assert.Error(NewSubscriptionManager().Restore())
+}
+
+func TestSubscriptionManagerSaveFails(t *testing.T) {
+	assert := assert.New(t)
+
+	store := &failingSubscriptionStore{memorySubscriptionStore: make(memorySubscriptionStore)}
+	m := NewSubscriptionManager(WithPersistence(store))
+	assert.NoError(m.Subscribe("alice", []string{"news"}))
+
+	store.err = errors.New("disk full")
+	assert.ErrorContains(m.Subscribe("alice", []string{"sports"}), "disk full")
+	assert.ErrorContains(m.Unsubscribe("alice", []string{"news"}), "disk full")
+	assert.Equal([]string{"news"}, m.ListSubscriptions("alice"))
+	assert.Empty(m.ListSubscribers("sports"))
+	assert.Equal([]string{"alice"}, m.ListSubscribers("news"))
+
+	store.err = nil
+	assert.NoError(m.Subscribe("alice", []string{"sports"}))
+	assert.Equal(memorySubscriptionStore{"alice": {"news", "sports"}}, store.memorySubscriptionStore)
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	sse "github.com/jlburkhead/go-sse/pkg"
+)
+
+// EventFormat is an encoding of events sent to clients
+type EventFormat int
+
+const (
+	// FormatSSE is the event stream format
+	FormatSSE EventFormat = iota
+	// FormatJSONStream is the event stream format with each event encoded as a JSON object in a single data field
+	FormatJSONStream
+	// FormatNDJSON is newline delimited JSON objects
+	FormatNDJSON
+)
+
+// ContentType returns the media type of the format
+func (f EventFormat) ContentType() string {
+	switch f {
+	case FormatJSONStream:
+		return "application/stream+json"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	}
+	return "text/event-stream"
+}
+
+// LineEnding is the line separator of the event stream format
+type LineEnding int
+
+const (
+	// LF separates lines with a line feed. It's the default and the most widely supported.
+	LF LineEnding = iota
+	// CRLF separates lines with a carriage return and line feed pair.
+	// Some strict interpretations of the specification require it.
+	CRLF
+	// CR separates lines with a carriage return
+	CR
+)
+
+func (le LineEnding) String() string {
+	switch le {
+	case CRLF:
+		return "\r\n"
+	case CR:
+		return "\r"
+	}
+	return "\n"
+}
+
+type jsonEvent struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Data    string `json:"data"`
+	Version int    `json:"version,omitempty"`
+}
+
+func newJSONEvent(event sse.Event) jsonEvent {
+	return jsonEvent{event.ID, event.Type, event.Data, event.Version}
+}
+
+func (e jsonEvent) event() sse.Event {
+	return sse.Event{ID: e.ID, Type: e.Type, Data: e.Data, Version: e.Version}
+}
+
+// marshal encodes an event, separating the lines of the event stream formats with le
+func (f EventFormat) marshal(event sse.Event, le LineEnding) ([]byte, error) {
+	switch f {
+	case FormatJSONStream:
+		b, err := json.Marshal(newJSONEvent(event))
+		if err != nil {
+			return nil, err
+		}
+		return marshalSSE(sse.Event{Data: string(b), ID: event.ID, Version: event.Version, Ctx: event.Ctx}, le), nil
+	case FormatNDJSON:
+		b, err := json.Marshal(newJSONEvent(event))
+		return append(b, '\n'), err
+	}
+	return marshalSSE(event, le), nil
+}
+
+// lineBreaks normalizes the line breaks the event stream format recognizes to LF
+var lineBreaks = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// splitLines splits s on CRLF, CR and LF, which all end a line of the event stream format
+func splitLines(s string) []string {
+	return strings.Split(lineBreaks.Replace(s), "\n")
+}
+
+// singleLine strips line breaks from the value of a field that can't span lines,
+// which would otherwise end the field and inject whatever follows
+var singleLine = strings.NewReplacer("\r", "", "\n", "")
+
+func marshalSSE(event sse.Event, le LineEnding) []byte {
+	eol := le.String()
+
+	var buf bytes.Buffer
+	if key, ok := IdempotencyKey(event); ok {
+		buf.WriteString(": idempotency-key: " + singleLine.Replace(key) + eol)
+	}
+	if event.ID != "" {
+		buf.WriteString("id: " + singleLine.Replace(event.ID) + eol)
+	}
+	if event.Version != 0 {
+		buf.WriteString("version: " + strconv.Itoa(event.Version) + eol)
+	}
+	if event.Type != "" && event.Type != "message" {
+		buf.WriteString("event: " + singleLine.Replace(event.Type) + eol)
+	}
+	for _, line := range splitLines(event.Data) {
+		buf.WriteString("data: " + line + eol)
+	}
+	buf.WriteString(eol)
+	return buf.Bytes()
+}
+
+// EventWriter writes events in the event stream format https://www.w3.org/TR/2015/REC-eventsource-20150203/#parsing-an-event-stream
+type EventWriter struct {
+	w          io.Writer
+	lineEnding LineEnding
+}
+
+// NewEventWriter constructs an EventWriter writing to w
+//
+// w is flushed after every event if it implements http.Flusher.
+func NewEventWriter(w io.Writer) *EventWriter {
+	return &EventWriter{w: w}
+}
+
+// SetLineEnding sets the line separator of the events written, which is LF by default
+func (w *EventWriter) SetLineEnding(le LineEnding) {
+	w.lineEnding = le
+}
+
+// WriteEvent writes a single event
+func (w *EventWriter) WriteEvent(event sse.Event) error {
+	return w.write(marshalSSE(event, w.lineEnding))
+}
+
+// WriteComment writes a comment, which clients ignore, with a line for each line of text.
+//
+// Comments can be sent as heartbeats to keep idle connections open.
+func (w *EventWriter) WriteComment(text string) error {
+	eol := w.lineEnding.String()
+
+	var buf bytes.Buffer
+	for _, line := range splitLines(text) {
+		buf.WriteString(": " + line + eol)
+	}
+	return w.write(buf.Bytes())
+}
+
+// WriteEvent writes a single event to w in the event stream format, flushing w if it implements http.Flusher
+func WriteEvent(w io.Writer, event sse.Event) error {
+	return NewEventWriter(w).WriteEvent(event)
+}
+
+// WriteComment writes a comment to w in the event stream format, flushing w if it implements http.Flusher
+func WriteComment(w io.Writer, text string) error {
+	return NewEventWriter(w).WriteComment(text)
+}
+
+// writeRetry writes a retry field setting the client's reconnection time in milliseconds
+func (w *EventWriter) writeRetry(ms int) error {
+	eol := w.lineEnding.String()
+	return w.write([]byte("retry: " + strconv.Itoa(ms) + eol + eol))
+}
+
+func (w *EventWriter) write(b []byte) error {
+	if err := w.writeUnflushed(b); err != nil {
+		return err
+	}
+	w.flush()
+	return nil
+}
+
+// writeUnflushed writes b without flushing, so several events can be flushed at once
+func (w *EventWriter) writeUnflushed(b []byte) error {
+	_, err := w.w.Write(b)
+	return err
+}
+
+func (w *EventWriter) flush() {
+	if flusher, ok := w.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,87 @@
+package sse
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ShardedClient consumes a stream in parallel by splitting its events between shards
+//
+// Events are assigned to a shard by the hash of their event ID, so events with the same ID are always consumed in order by the same shard.
+//
+// Shards share a single connection, so they can't resume independently: the stream reconnects with the last event ID
+// it received, whichever shard that was routed to. ShardedClient.LastEventID reports each shard's progress.
+type ShardedClient struct {
+	stream    Stream
+	consumers []chan Event
+
+	mu           sync.Mutex
+	lastEventIDs []string
+}
+
+// NewShardedClient constructs a ShardedClient that splits the events of a stream from one of resources between shards consumers
+func NewShardedClient(resources []string, shards int, opts ...Option) (*ShardedClient, error) {
+	if shards <= 0 {
+		return nil, errors.Errorf("invalid number of shards %d", shards)
+	}
+
+	c := &ShardedClient{
+		consumers:    make([]chan Event, shards),
+		lastEventIDs: make([]string, shards),
+	}
+	for i := range c.consumers {
+		c.consumers[i] = make(chan Event)
+	}
+
+	s, err := NewLoadBalanced(resources, RoundRobin, append(opts, func(s *Stream) { s.route = c.route })...)
+	if err != nil {
+		return nil, err
+	}
+	c.stream = s
+
+	go func() {
+		for range s.Events() {
+		}
+		for _, consumer := range c.consumers {
+			close(consumer)
+		}
+	}()
+
+	return c, nil
+}
+
+// Consumer returns the channel of events for a shard
+//
+// It is closed when the stream is. The stream blocks until every event is consumed, so every shard must be consumed.
+func (c *ShardedClient) Consumer(shard int) <-chan Event {
+	return c.consumers[shard]
+}
+
+// LastEventID returns the last event ID routed to a shard.
+//
+// It isn't sent when the stream reconnects, see ShardedClient.
+func (c *ShardedClient) LastEventID(shard int) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lastEventIDs[shard]
+}
+
+// Stream returns the underlying stream
+func (c *ShardedClient) Stream() Stream {
+	return c.stream
+}
+
+func (c *ShardedClient) route(event Event, lastEventID string) chan<- Event {
+	h := fnv.New32a()
+	h.Write([]byte(lastEventID))
+	shard := int(h.Sum32() % uint32(len(c.consumers)))
+
+	c.mu.Lock()
+	c.lastEventIDs[shard] = lastEventID
+	c.mu.Unlock()
+
+	return c.consumers[shard]
+}
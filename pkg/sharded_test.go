@@ -0,0 +1,63 @@
+package sse
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 10; i++ {
+			fmt.Fprintf(w, "id: %d\ndata: %d\n\n", i%3, i)
+		}
+	}))
+	defer server.Close()
+
+	const shards = 2
+	c, err := NewShardedClient([]string{server.URL}, shards, WithMaxReconnectAttempts(0))
+	require.NoError(t, err)
+
+	shardOf := func(id string) int {
+		h := fnv.New32a()
+		h.Write([]byte(id))
+		return int(h.Sum32() % shards)
+	}
+
+	received := make([][]string, shards)
+	var wg sync.WaitGroup
+	for shard := 0; shard < shards; shard++ {
+		wg.Add(1)
+		go func(shard int) {
+			defer wg.Done()
+			for event := range c.Consumer(shard) {
+				received[shard] = append(received[shard], event.Data)
+			}
+		}(shard)
+	}
+	wg.Wait()
+
+	expected := make([][]string, shards)
+	for i := 0; i < 10; i++ {
+		shard := shardOf(fmt.Sprint(i % 3))
+		expected[shard] = append(expected[shard], fmt.Sprint(i))
+	}
+	assert.Equal(t, expected, received)
+
+	for shard := 0; shard < shards; shard++ {
+		if len(received[shard]) != 0 {
+			assert.Equal(t, shard, shardOf(c.LastEventID(shard)))
+		}
+	}
+}
+
+func TestShardedClientInvalidShards(t *testing.T) {
+	_, err := NewShardedClient([]string{"http://localhost"}, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,53 @@
+package sse
+
+import "sync"
+
+// ReadyState represents the state of the connection https://www.w3.org/TR/2015/REC-eventsource-20150203/#dom-eventsource-readystate
+type ReadyState int
+
+const (
+	// Connecting means the connection has not yet been established, or it was closed and the user agent is reconnecting.
+	Connecting ReadyState = iota
+	// Open means the user agent has an open connection and is dispatching events as it receives them.
+	Open
+	// Closed means the connection is not open, and the user agent is not trying to reconnect.
+	Closed
+)
+
+func (r ReadyState) String() string {
+	switch r {
+	case Connecting:
+		return "CONNECTING"
+	case Open:
+		return "OPEN"
+	case Closed:
+		return "CLOSED"
+	}
+	return "UNKNOWN"
+}
+
+// readyState is shared between copies of a Stream
+type readyState struct {
+	mu    sync.Mutex
+	value ReadyState
+}
+
+func (s Stream) setReadyState(to ReadyState, err error) {
+	s.readyState.mu.Lock()
+	from := s.readyState.value
+	s.readyState.value = to
+	s.stats.open.Store(to == Open)
+	s.readyState.mu.Unlock()
+
+	if from != to && s.stateChangeHook != nil {
+		s.stateChangeHook(from, to, err)
+	}
+}
+
+// State returns the current ready state of the stream
+func (s Stream) State() ReadyState {
+	s.readyState.mu.Lock()
+	defer s.readyState.mu.Unlock()
+
+	return s.readyState.value
+}
@@ -0,0 +1,96 @@
+package sse
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stateChange struct {
+	from, to ReadyState
+	err      error
+}
+
+func TestReadyState(t *testing.T) {
+	assert.Equal(t, []int{0, 1, 2}, []int{int(Connecting), int(Open), int(Closed)})
+	assert.Equal(t, []string{"CONNECTING", "OPEN", "CLOSED", "UNKNOWN"},
+		[]string{Connecting.String(), Open.String(), Closed.String(), ReadyState(3).String()})
+	assert.Equal(t, Connecting, newStream("").State())
+}
+
+func TestStateChangeHook(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: foo\n\n"))
+	}))
+	defer server.Close()
+
+	changes := make(chan stateChange, 2)
+	s, err := New(server.URL, WithMaxReconnectAttempts(0), WithStateChangeHook(func(from, to ReadyState, err error) {
+		changes <- stateChange{from, to, err}
+	}))
+	require.NoError(err)
+
+	for range s.Events() {
+	}
+
+	assert.Equal(stateChange{Connecting, Open, nil}, <-changes)
+	assert.Equal(stateChange{Open, Closed, nil}, <-changes)
+}
+
+func TestStateChangeHookConnectError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	var changes []stateChange
+	_, err := New(server.URL, WithStateChangeHook(func(from, to ReadyState, err error) {
+		changes = append(changes, stateChange{from, to, err})
+	}))
+	require.Error(t, err)
+
+	assert.Equal(t, []stateChange{{Connecting, Closed, err}}, changes)
+}
+
+func TestStateChangeHookClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: foo\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	changes := make(chan stateChange, 2)
+	s, err := New(server.URL, WithStateChangeHook(func(from, to ReadyState, err error) {
+		changes <- stateChange{from, to, err}
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, Open, s.State())
+
+	require.NoError(t, s.Close())
+	assert.Equal(t, Closed, s.State())
+	assert.Equal(t, stateChange{Connecting, Open, nil}, <-changes)
+	assert.Equal(t, stateChange{Open, Closed, ErrStreamClosed}, <-changes)
+}
+
+func TestStateChangeHookParseError(t *testing.T) {
+	var changes []stateChange
+	s := newStream("", WithMaxReconnectAttempts(0), WithStateChangeHook(func(from, to ReadyState, err error) {
+		changes = append(changes, stateChange{from, to, err})
+	}))
+	s.setReadyState(Open, nil)
+
+	err := s.parse(ioutil.NopCloser(strings.NewReader("data: \x80\n\n")))
+	require.Error(t, err)
+
+	require.Len(t, changes, 2)
+	assert.Equal(t, stateChange{Open, Closed, err}, changes[1])
+}
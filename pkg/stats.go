@@ -0,0 +1,84 @@
+package sse
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// StreamStats is a snapshot of the health of a Stream
+type StreamStats struct {
+	// EventsReceived is the number of events dispatched
+	EventsReceived uint64
+	// BytesReceived is the number of bytes read from the resource
+	BytesReceived uint64
+	// ConnectCount is the number of successful connections to the resource
+	ConnectCount uint64
+	// ReconnectCount is the number of attempts to reconnect, successful or not
+	ReconnectCount uint64
+	// LastConnectedAt is when the last successful connection was made
+	LastConnectedAt time.Time
+	// LastEventTime is when the last event was dispatched
+	LastEventTime time.Time
+	// Uptime is how long the current connection has been open, or zero if it isn't open
+	Uptime time.Duration
+}
+
+// stats is shared between copies of a Stream
+type stats struct {
+	eventsReceived atomic.Uint64
+	bytesReceived  atomic.Uint64
+	connectCount   atomic.Uint64
+	reconnectCount atomic.Uint64
+	lastEventTime  atomic.Int64
+	connectedAt    atomic.Int64
+	// open mirrors whether the ready state is Open, so Stats doesn't take the ready state's lock
+	open atomic.Bool
+}
+
+// Stats returns a snapshot of the stream's statistics. It's read from atomics without taking any locks.
+func (s Stream) Stats() StreamStats {
+	stats := StreamStats{
+		EventsReceived: s.stats.eventsReceived.Load(),
+		BytesReceived:  s.stats.bytesReceived.Load(),
+		ConnectCount:   s.stats.connectCount.Load(),
+		ReconnectCount: s.stats.reconnectCount.Load(),
+	}
+	if t := s.stats.lastEventTime.Load(); t != 0 {
+		stats.LastEventTime = time.Unix(0, t)
+	}
+
+	if t := s.stats.connectedAt.Load(); t != 0 {
+		stats.LastConnectedAt = time.Unix(0, t)
+		if s.stats.open.Load() {
+			stats.Uptime = s.clock.Now().Sub(stats.LastConnectedAt)
+		}
+	}
+
+	return stats
+}
+
+func (s *stats) connected(now time.Time) {
+	s.connectCount.Add(1)
+	s.connectedAt.Store(now.UnixNano())
+}
+
+func (s *stats) reconnecting() {
+	s.reconnectCount.Add(1)
+}
+
+func (s *stats) eventReceived(now time.Time) {
+	s.eventsReceived.Add(1)
+	s.lastEventTime.Store(now.UnixNano())
+}
+
+type countingReader struct {
+	io.Reader
+	stats *stats
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.stats.bytesReceived.Add(uint64(n))
+	return n, err
+}
@@ -0,0 +1,72 @@
+package sse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	input := "data: foo\n\n: comment\n\ndata: bar\n\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(input))
+	}))
+	defer server.Close()
+
+	s, err := New(server.URL, WithMaxReconnectAttempts(0))
+	require.NoError(err)
+
+	<-s.Events()
+	stats := s.Stats()
+	assert.Equal(uint64(1), stats.ConnectCount)
+	assert.NotZero(stats.Uptime)
+
+	for range s.Events() {
+	}
+
+	stats = s.Stats()
+	assert.Equal(uint64(2), stats.EventsReceived)
+	assert.Equal(uint64(len(input)), stats.BytesReceived)
+	assert.Equal(uint64(1), stats.ConnectCount)
+	assert.Zero(stats.ReconnectCount)
+	assert.False(stats.LastConnectedAt.IsZero())
+	assert.False(stats.LastEventTime.IsZero())
+	assert.Zero(stats.Uptime)
+}
+
+func TestStatsReconnects(t *testing.T) {
+	var connections atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if connections.Add(1) == 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("retry: 1\ndata: a\n\n"))
+	}))
+	defer server.Close()
+
+	c := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	s, err := New(server.URL, WithClock(c), WithMaxReconnectAttempts(2))
+	require.NoError(t, err)
+	<-s.Events()
+	for _, delay := range []time.Duration{time.Millisecond, 2 * time.Millisecond} {
+		<-s.Reconnects()
+		require.Eventually(t, func() bool { return c.Waiters() == 1 }, time.Second, time.Millisecond)
+		c.Advance(delay)
+	}
+	<-s.Events()
+
+	stats := s.Stats()
+	assert.Equal(t, uint64(2), stats.ConnectCount)
+	assert.Equal(t, uint64(2), stats.ReconnectCount)
+	assert.True(t, c.Now().Equal(stats.LastConnectedAt))
+	require.NoError(t, s.Close())
+}
@@ -1,14 +1,25 @@
 // Package sse implements a user agent for the Server-Sent Events Protocol https://www.w3.org/TR/2015/REC-eventsource-20150203/
 //
-// The major parts of the protocol that aren't implemented are reestablishing the connection and some of the error events outlined in https://www.w3.org/TR/2015/REC-eventsource-20150203/#processing-model.
+// Streams reestablish the connection when it ends, sending the last event ID they received.
+// Some of the error events outlined in https://www.w3.org/TR/2015/REC-eventsource-20150203/#processing-model aren't implemented.
 package sse
 
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"crypto/cipher"
+	"hash"
 	"io"
+	"log/slog"
+	"math"
 	"net/http"
+	"net/http/httptrace"
 	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
 
 	"github.com/pkg/errors"
 	"golang.org/x/text/encoding"
@@ -20,42 +31,219 @@ var eventType = []byte("event")
 var dataType = []byte("data")
 var idType = []byte("id")
 var retryType = []byte("retry")
+var versionType = []byte("version")
+
+// ErrStreamTimeout is the reason a stream closes when the timeout set by WithTimeout elapses
+var ErrStreamTimeout = errors.New("stream timeout")
+
+// ErrStreamClosed is the reason a stream closes when Stream.Close is called
+var ErrStreamClosed = errors.New("stream closed")
+
+// ErrEventProcessingTimeout is counted when an event is dropped because it wasn't received within the event processing timeout
+var ErrEventProcessingTimeout = errors.New("event processing timeout")
+
+// ErrDispatchTimeout closes a stream when an event isn't received within the dispatch timeout
+var ErrDispatchTimeout = errors.New("event dispatch timeout")
+
+// errorBufferSize is how many errors Stream.Errors buffers before dropping them
+const errorBufferSize = 16
+
+// maxDrainedBodySize is how much of an error response's body is read so its connection can be reused
+const maxDrainedBodySize = 4 * 1024
+
+const (
+	// defaultScannerBufferSize is the size of the buffer lines are read into by default
+	defaultScannerBufferSize = 64 * 1024
+	// defaultMaxScannerBufferSize is the longest line that can be read by default
+	defaultMaxScannerBufferSize = 1024 * 1024
+)
 
 // Event represents a Server-Sent Event
 type Event struct {
 	Type string
 	Data string
+	// ID is the last event ID when the event was dispatched, it's empty if the server hasn't sent one
+	ID string
+	// Version is the schema version set by the event's version field, it's 0 if the event doesn't have one
+	Version int
+
+	// Ctx carries event level values attached by Stream.WithEventContext, or the trace context when there's a Tracer
+	Ctx context.Context
+}
+
+// Equal reports whether e and other have the same type, data, ID and version.
+//
+// Ctx isn't compared, it carries values attached to the event rather than what was received.
+func (e Event) Equal(other Event) bool {
+	return e.Type == other.Type && e.Data == other.Data && e.ID == other.ID && e.Version == other.Version
+}
+
+// EventsEqual reports whether a and b have the same length and equal events in the same order, see Event.Equal
+func EventsEqual(a, b []Event) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
 }
 
 // Stream reads and parses events from a resource
 type Stream struct {
 	resource   string
 	events     chan Event
+	errs       chan error
+	reconnects chan ReconnectEvent
 	httpClient *http.Client
+	headers    http.Header
+
+	readyState      *readyState
+	stateChangeHook func(from, to ReadyState, err error)
+	// connectionErrorHook is set by OnConnectionError after the stream has started, so it's shared between copies of the stream
+	connectionErrorHook *atomic.Pointer[func(attempt int, err error, willRetry bool)]
+
+	lastEventIDStore LastEventIDStore
+
+	eventProcessingTimeout  time.Duration
+	dispatchTimeout         time.Duration
+	eventProcessingTimeouts *atomic.Uint64
+
+	stats *stats
+	clock Clock
+
+	httpTrace *httptrace.ClientTrace
+
+	tracer Tracer
+	// connectionCtx is the context the tracer returned for the current connection
+	connectionCtx *context.Context
+
+	newDecoder func(io.Reader) Decoder
+
+	aead cipher.AEAD
+
+	zeroCopy bool
 
-	reconnectionTime int
+	hmacSecret []byte
+	hmacHash   func() hash.Hash
+
+	loadBalancer *loadBalancer
+	route        func(event Event, lastEventID string) chan<- Event
+
+	meta *sync.Map
+
+	transport   *http.Transport
+	httpVersion HTTPVersion
+
+	ackEndpoint      string
+	ackBatchSize     int
+	ackBatchInterval time.Duration
+	ackOnSuccess     func(id string)
+	ackOnFailure     func(id string, err error)
+	acker            *acker
+
+	// optionErr is set by options that couldn't be applied
+	optionErr error
+
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	timeout time.Duration
+
+	reconnectInterval    func(attempt int, serverHint time.Duration) time.Duration
+	maxReconnectAttempts int
+	urlRotator           func(attempt int) string
+	reconnectBackoffCap  time.Duration
+	// reconnectBackoffMultiplier is how much the wait grows after each failed reconnection attempt
+	reconnectBackoffMultiplier float64
+	jitter                     float64
+
+	rawFrameLogger func(frame []byte)
+	logger         *slog.Logger
+	dispatchOnEOF  bool
+
+	scannerBufferSize, maxScannerBufferSize int
+
+	proxyDetection          bool
+	proxyDetectionThreshold time.Duration
+	proxyBuffered           *atomic.Bool
+
+	tunnelDetection bool
+	tunneled        *atomic.Bool
+
+	charsetDetection bool
+	compression      bool
+
+	gaps *gapDetector
+
+	circuit *circuitBreaker
+
+	subscriptions *subscriptions
+
+	minVersion, maxVersion int
+
+	// reconnectionTime is in milliseconds and shared between copies of the stream
+	reconnectionTime *atomic.Int64
 	data             *bytes.Buffer
 	eventType        *bytes.Buffer
 	lastEventID      *bytes.Buffer
+	// eventVersion is set by the version field, which isn't part of the specification
+	eventVersion *int
+	// eventHasID is whether the event being parsed has an id field, rather than inheriting the last event ID
+	eventHasID *bool
+	// lastEventType is the type of the last event dispatched, reused while events have the same type
+	lastEventType *string
 }
 
 // New constructs a Stream for a resource
 //
 // Errors generated from creating the initial connection are returned.
 // Events are read from the channel returned by Stream.Events
-func New(resource string) (Stream, error) {
-	s := Stream{
-		resource:    resource,
-		events:      make(chan Event),
-		httpClient:  http.DefaultClient,
-		data:        new(bytes.Buffer),
-		eventType:   new(bytes.Buffer),
-		lastEventID: new(bytes.Buffer),
+func New(resource string, opts ...Option) (Stream, error) {
+	return NewWithContext(context.Background(), resource, opts...)
+}
+
+// NewWithContext constructs a Stream for a resource that is closed when ctx is done
+//
+// Every connection is made with ctx, and the channel returned by Stream.Events is closed once ctx is done.
+func NewWithContext(ctx context.Context, resource string, opts ...Option) (Stream, error) {
+	s := newStreamContext(ctx, resource, withDefaultOptions(opts)...)
+	if s.optionErr != nil {
+		return s, s.fail(s.optionErr)
 	}
 
-	r, err := s.connect()
+	if s.lastEventIDStore != nil {
+		id, err := s.lastEventIDStore.LastEventID()
+		if err != nil {
+			return s, s.fail(errors.Wrap(err, "loading last event id"))
+		}
+		if id != "" {
+			s.lastEventID.Reset()
+			s.lastEventID.WriteString(id)
+		}
+	}
+
+	r, err := s.connect(0)
 	if err != nil {
-		return s, err
+		return s, s.fail(err)
+	}
+	s.setReadyState(Open, nil)
+
+	if s.timeout > 0 {
+		go func() {
+			timeout, stop := newTimer(s.clock, s.timeout)
+			defer stop()
+			select {
+			case <-timeout:
+				s.cancel(ErrStreamTimeout)
+			case <-s.ctx.Done():
+			}
+		}()
+	}
+
+	if s.ackEndpoint != "" {
+		s.acker = s.newAcker()
 	}
 
 	go s.parse(r)
@@ -63,34 +251,222 @@ func New(resource string) (Stream, error) {
 	return s, nil
 }
 
+// ChainContext closes the stream once ctx is done, as if the context it was constructed with was done
+func (s Stream) ChainContext(ctx context.Context) {
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.cancel(context.Cause(ctx))
+		case <-s.ctx.Done():
+		}
+	}()
+}
+
+// Close stops the stream and waits for it to close, discarding the events it hasn't delivered.
+//
+// It's safe to call more than once and from multiple goroutines.
+func (s Stream) Close() error {
+	s.cancel(ErrStreamClosed)
+	for range s.events {
+	}
+	return nil
+}
+
+// ParseAll parses every event of a complete event stream read from r, such as a file, without connecting to a resource.
+//
+// Options affecting parsing, such as WithDispatchOnEOF, apply. The events parsed before an error reading r are returned with it.
+func ParseAll(r io.Reader, opts ...Option) ([]Event, error) {
+	s := newStream("", append(opts, WithMaxReconnectAttempts(0))...)
+	if s.optionErr != nil {
+		return nil, s.optionErr
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.parse(io.NopCloser(r))
+	}()
+
+	var events []Event
+	for event := range s.events {
+		events = append(events, event)
+	}
+	return events, <-errs
+}
+
+// fail closes a stream that couldn't be started
+func (s Stream) fail(err error) error {
+	s.cancel(err)
+	s.setReadyState(Closed, err)
+	s.reportError(err)
+	s.closeSubscriptions()
+	close(s.events)
+	close(s.reconnects)
+	close(s.errs)
+	return err
+}
+
+func newStream(resource string, opts ...Option) Stream {
+	return newStreamContext(context.Background(), resource, opts...)
+}
+
+func newStreamContext(ctx context.Context, resource string, opts ...Option) Stream {
+	s := Stream{
+		resource:   resource,
+		events:     make(chan Event),
+		errs:       make(chan error, errorBufferSize),
+		reconnects: make(chan ReconnectEvent, reconnectBufferSize),
+		httpClient: http.DefaultClient,
+		readyState: &readyState{value: Connecting},
+
+		eventProcessingTimeouts:    new(atomic.Uint64),
+		reconnectionTime:           new(atomic.Int64),
+		maxReconnectAttempts:       -1,
+		reconnectBackoffCap:        defaultReconnectBackoffCap,
+		reconnectBackoffMultiplier: defaultReconnectBackoffMultiplier,
+		proxyDetectionThreshold:    defaultProxyDetectionThreshold,
+		proxyBuffered:              new(atomic.Bool),
+		tunneled:                   new(atomic.Bool),
+		connectionErrorHook:        new(atomic.Pointer[func(attempt int, err error, willRetry bool)]),
+		subscriptions:              &subscriptions{byType: make(map[string]*subscription)},
+		stats:                      new(stats),
+		clock:                      RealClock{},
+		meta:                       new(sync.Map),
+		ackBatchSize:               defaultAckBatchSize,
+		ackBatchInterval:           defaultAckBatchInterval,
+		scannerBufferSize:          defaultScannerBufferSize,
+		maxScannerBufferSize:       defaultMaxScannerBufferSize,
+
+		data:        new(bytes.Buffer),
+		eventType:   new(bytes.Buffer),
+		lastEventID: new(bytes.Buffer),
+
+		eventVersion:  new(int),
+		eventHasID:    new(bool),
+		lastEventType: new(string),
+		maxVersion:    math.MaxInt,
+	}
+
+	s.reconnectionTime.Store(defaultReconnectionTime)
+	s.ctx, s.cancel = context.WithCancelCause(ctx)
+
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	return s
+}
+
 // Events returns a channel to read the event stream
 func (s Stream) Events() <-chan Event {
 	return s.events
 }
 
-func (s Stream) connect() (io.ReadCloser, error) {
-	req, err := http.NewRequest(http.MethodGet, s.resource, nil)
+// Errors returns a channel of the errors the stream runs into
+//
+// Errors ending a connection the stream reconnects from, failed reconnection attempts and the error closing the stream are sent.
+// Errors are dropped while the channel is full, it's closed once the channel returned by Events is.
+func (s Stream) Errors() <-chan error {
+	return s.errs
+}
+
+// reportError sends an error to the channel returned by Errors without blocking
+func (s Stream) reportError(err error) {
+	s.debug("error", slog.String("resource", s.resource), slog.Any("error", err))
+	select {
+	case s.errs <- err:
+	default:
+	}
+}
+
+// EventProcessingTimeouts returns the number of events dropped because of ErrEventProcessingTimeout
+func (s Stream) EventProcessingTimeouts() uint64 {
+	return s.eventProcessingTimeouts.Load()
+}
+
+// connect opens a connection to the resource, attempt counts the reconnection attempts since the last connection ended
+func (s Stream) connect(attempt int) (io.ReadCloser, error) {
+	resource := s.resource
+	switch {
+	case attempt > 0 && s.urlRotator != nil:
+		resource = s.urlRotator(attempt)
+	case s.loadBalancer != nil:
+		resource = s.loadBalancer.pick()
+	}
+
+	s.debug("connecting", slog.String("resource", resource), slog.Int("attempt", attempt))
+	if s.tracer == nil {
+		body, _, err := s.request(s.ctx, resource)
+		return body, err
+	}
+	ctx, end := s.tracer.Connect(s.ctx, resource, attempt)
+	*s.connectionCtx = ctx
+	body, status, err := s.request(ctx, resource)
+	end(status, err)
+	return body, err
+}
+
+// request requests the resource, returning the body of the response and its status code, which is 0 if there isn't one
+func (s Stream) request(ctx context.Context, resource string) (io.ReadCloser, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, resource, nil)
 	if err != nil {
-		return nil, errors.Wrap(err, "creating http request")
+		return nil, 0, errors.Wrap(err, "creating http request")
+	}
+	if s.httpTrace != nil {
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), s.httpTrace))
 	}
 
-	req.Header.Add("Content-Type", "text/event-stream")
+	req.Header.Add("Accept", "text/event-stream")
 	req.Header.Add("Cache-Control", "no-cache")
+	if s.compression {
+		req.Header.Add("Accept-Encoding", "gzip, deflate")
+	}
 	if s.lastEventID.Len() != 0 {
 		req.Header.Add("Last-Event-ID", s.lastEventID.String())
 	}
+	for name, values := range s.headers {
+		req.Header[name] = values
+	}
+	if s.hmacHash != nil {
+		s.signRequest(req)
+	}
 
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		return nil, errors.Wrap(err, "http error")
+		return nil, 0, errors.Wrap(err, "http error")
 	}
 
-	// TODO: other status codes
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.Errorf("unexpected status code %v", resp.StatusCode)
+		// Drain the body so the connection can be reused by the next attempt
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainedBodySize))
+		resp.Body.Close()
+		err := errors.Errorf("unexpected status code %v", resp.StatusCode)
+		if resp.StatusCode == http.StatusTooManyRequests {
+			if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), s.clock.Now()); ok {
+				return nil, resp.StatusCode, &retryAfterError{error: err, delay: delay}
+			}
+		}
+		return nil, resp.StatusCode, err
+	}
+	s.stats.connected(s.clock.Now())
+	s.checkHTTPVersion(resp)
+	if s.tunnelDetection {
+		s.detectTunnel(resp)
 	}
 
-	return resp.Body, nil
+	body := resp.Body
+	if s.compression {
+		body = decompress(resp, body)
+	}
+	if s.charsetDetection {
+		body = s.decodeCharset(resp, body)
+	}
+	if s.proxyDetection {
+		body = s.detectProxy(body)
+	}
+	if s.loadBalancer != nil {
+		return s.loadBalancer.track(resource, body), resp.StatusCode, nil
+	}
+	return body, resp.StatusCode, nil
 }
 
 func splitLines(data []byte, atEOF bool) (int, []byte, error) {
@@ -99,10 +475,14 @@ func splitLines(data []byte, atEOF bool) (int, []byte, error) {
 		switch b {
 		case '\r':
 			if i+1 >= len(data) {
+				// A CR at the end of the data could be the start of a CRLF pair, unless there's no more data
+				if atEOF {
+					return i + 1, data[:i], nil
+				}
 				return 0, nil, nil
 			}
 			if data[i+1] == '\n' {
-				return i + 2, data[:i+1], nil
+				return i + 2, data[:i], nil
 			}
 			return i + 1, data[:i], nil
 		case '\n':
@@ -113,20 +493,62 @@ func splitLines(data []byte, atEOF bool) (int, []byte, error) {
 	return 0, nil, nil
 }
 
-func (s *Stream) parse(reader io.ReadCloser) error {
-	// TODO: reconnect
+func (s *Stream) parse(reader io.ReadCloser) (err error) {
+	defer close(s.errs)
+	defer close(s.reconnects)
 	defer close(s.events)
+	defer s.closeSubscriptions()
+	defer func() {
+		// Reads fail once the stream's context is done, report why it's done instead
+		if err != nil && s.ctx.Err() != nil {
+			err = context.Cause(s.ctx)
+		}
+		if err != nil {
+			s.reportError(err)
+		}
+		s.cancel(err)
+		s.setReadyState(Closed, err)
+	}()
+	if s.acker != nil {
+		defer s.acker.close()
+	}
+
+	for {
+		var reconnect bool
+		reconnect, err = s.read(reader)
+		if !reconnect || s.ctx.Err() != nil {
+			return err
+		}
+		if s.maxReconnectAttempts == 0 {
+			s.reportConnectionError(0, err, false)
+			return err
+		}
+		if reader, err = s.reconnect(err); err != nil {
+			return err
+		}
+		if s.gaps != nil {
+			s.gaps.reconnected = true
+		}
+	}
+}
+
+// read parses the events of a single connection, reporting whether the stream can reconnect when it ends
+func (s *Stream) read(reader io.ReadCloser) (bool, error) {
 	defer reader.Close()
 
+	buffered := bufio.NewReader(countingReader{reader, s.stats})
+	if s.newDecoder != nil {
+		return s.decode(s.newDecoder(buffered))
+	}
+
 	// One leading U+FEFF BYTE ORDER MARK character must be ignored if any are present.
-	buffered := bufio.NewReader(reader)
 	bom, err := buffered.Peek(len(utf8BOM))
 	if err != nil {
-		return err
+		return true, err
 	}
 	if bytes.Equal(utf8BOM, bom) {
 		if _, err := buffered.Discard(len(utf8BOM)); err != nil {
-			return err
+			return true, err
 		}
 	}
 
@@ -134,21 +556,123 @@ func (s *Stream) parse(reader io.ReadCloser) error {
 	r := transform.NewReader(buffered, encoding.UTF8Validator)
 
 	scanner := bufio.NewScanner(r)
-	scanner.Split(splitLines)
+	scanner.Buffer(make([]byte, s.scannerBufferSize), s.maxScannerBufferSize)
+	var frame []byte
+	if s.rawFrameLogger != nil {
+		scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+			advance, token, err := splitLines(data, atEOF)
+			if token != nil {
+				frame = append(frame, data[:advance]...)
+			}
+			return advance, token, err
+		})
+	} else {
+		scanner.Split(splitLines)
+	}
 
 	for scanner.Scan() {
-		s.interpret(scanner.Bytes())
+		line := scanner.Bytes()
+		if s.rawFrameLogger != nil && len(line) == 0 {
+			s.rawFrameLogger(frame)
+			frame = nil
+		}
+		if err := s.interpret(line); err != nil {
+			return false, err
+		}
+	}
+
+	err = scanner.Err()
+	if err == nil && s.dispatchOnEOF {
+		if err := s.dispatch(); err != nil {
+			return false, err
+		}
 	}
+	// Once the end of the file is reached, any pending data must be discarded.
+	// (If the file ends in the middle of an event, before the final empty line, the incomplete event is not dispatched.)
+	s.resetEvent()
+	return true, err
+}
+
+// reconnect connects to the resource again after its connection ended with err,
+// waiting before each attempt and giving up after the maximum number of attempts
+func (s *Stream) reconnect(err error) (io.ReadCloser, error) {
+	s.setReadyState(Connecting, err)
+	for attempt := 1; ; attempt++ {
+		interval := time.Duration(-1)
+		if s.maxReconnectAttempts < 0 || attempt <= s.maxReconnectAttempts {
+			interval = s.reconnectDelay(attempt)
+		}
+		// Wait as long as the server asked when it's rate limiting, backing off as usual after that
+		var retryAfter *retryAfterError
+		if interval >= 0 && errors.As(err, &retryAfter) {
+			interval = retryAfter.delay
+		}
+		if interval >= 0 && s.circuit != nil {
+			if interval = s.circuit.delay(attempt-1, interval); interval < 0 {
+				s.reportConnectionError(attempt-1, err, false)
+				s.reportError(err)
+				return nil, ErrCircuitOpen
+			}
+		}
+		s.reportConnectionError(attempt-1, err, interval >= 0)
+		if interval < 0 {
+			return nil, err
+		}
+		// The previous error is only reported once another attempt follows it, the last one closes the stream
+		if err != nil {
+			s.reportError(err)
+		}
+		s.reportReconnect(ReconnectEvent{Attempt: attempt, Delay: interval, Reason: err, At: s.clock.Now()})
+		s.debug("reconnecting",
+			slog.String("resource", s.resource),
+			slog.Int("attempt", attempt),
+			slog.Int64("reconnection_time_ms", interval.Milliseconds()),
+		)
+
+		wait, stop := newTimer(s.clock, interval)
+		select {
+		case <-s.ctx.Done():
+			stop()
+			return nil, context.Cause(s.ctx)
+		case <-wait:
+		}
 
-	return scanner.Err()
+		if s.circuit != nil {
+			s.circuit.probe()
+		}
+		s.stats.reconnecting()
+		var r io.ReadCloser
+		if r, err = s.connect(attempt); err == nil {
+			if s.circuit != nil {
+				s.circuit.reset()
+			}
+			s.setReadyState(Open, nil)
+			return r, nil
+		}
+	}
 }
 
-func (s *Stream) interpret(line []byte) {
+func (s *Stream) decode(decoder Decoder) (bool, error) {
+	for {
+		event, err := decoder.Decode()
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return true, err
+		}
+		if err := s.deliver(event, event.ID != ""); err != nil {
+			return false, err
+		}
+	}
+}
+
+func (s *Stream) interpret(line []byte) error {
 	switch {
 	case len(line) == 0:
 		// If the line is empty (a blank line)
 		// Dispatch the event, as defined below.
-		s.dispatch()
+		return s.dispatch()
 	case line[0] == ':':
 		// If the line starts with a U+003A COLON character (:)
 		// Ignore the line.
@@ -157,11 +681,10 @@ func (s *Stream) interpret(line []byte) {
 		field, value := line, []byte(nil)
 		// Collect the characters on the line before the first U+003A COLON character (:), and let field be that string.
 		// Collect the characters on the line after the first U+003A COLON character (:), and let value be that string.
-		if split := bytes.SplitN(field, []byte{':'}, 2); len(split) == 2 {
-			field = split[0]
-			value = split[1]
+		if before, after, found := bytes.Cut(line, []byte{':'}); found {
+			field, value = before, after
 			// If value starts with a U+0020 SPACE character, remove it from value.
-			if value[0] == ' ' {
+			if len(value) > 0 && value[0] == ' ' {
 				value = value[1:]
 			}
 		}
@@ -172,6 +695,7 @@ func (s *Stream) interpret(line []byte) {
 		// Process the field using the steps described below, using the whole line as the field name, and the empty string as the field value.
 		s.process(field, value)
 	}
+	return nil
 }
 
 // https: //www.w3.org/TR/2015/REC-eventsource-20150203/#processField
@@ -195,6 +719,7 @@ func (s *Stream) process(name, value []byte) {
 	if bytes.Equal(idType, name) {
 		s.lastEventID.Reset()
 		s.lastEventID.Write(value)
+		*s.eventHasID = true
 		return
 	}
 	// If the field name is "retry"
@@ -202,7 +727,15 @@ func (s *Stream) process(name, value []byte) {
 	if bytes.Equal(retryType, name) {
 		reconnectionTime, err := strconv.Atoi(string(value))
 		if err == nil && reconnectionTime >= 0 {
-			s.reconnectionTime = reconnectionTime
+			s.reconnectionTime.Store(int64(reconnectionTime))
+		}
+		return
+	}
+	// If the field name is "version", an extension to the specification
+	// If the field value is an integer set the event version to it. Otherwise, ignore the field.
+	if bytes.Equal(versionType, name) {
+		if version, err := strconv.Atoi(string(value)); err == nil {
+			*s.eventVersion = version
 		}
 		return
 	}
@@ -211,21 +744,27 @@ func (s *Stream) process(name, value []byte) {
 	// The field is ignored.
 }
 
+// resetEvent empties the buffers of the event being parsed
+func (s Stream) resetEvent() {
+	s.data.Reset()
+	s.eventType.Reset()
+	*s.eventVersion = 0
+	*s.eventHasID = false
+}
+
 // https://www.w3.org/TR/2015/REC-eventsource-20150203/#dispatchMessage
-func (s Stream) dispatch() {
+func (s Stream) dispatch() error {
 	// 1. Set the last event ID string of the event source to value of the last event ID buffer.
 	// The buffer does not get reset, so the last event ID string of the event source remains set to this value until the next time it is set by the server.
 
 	// 2. If the data buffer is an empty string, set the data buffer and the event type buffer to the empty string and abort these steps.
 	if s.data.Len() == 0 {
-		s.data.Reset()
-		s.eventType.Reset()
-		return
+		s.resetEvent()
+		return nil
 	}
 
 	// 3. If the data buffer's last character is a U+000A LINE FEED (LF) character, then remove the last character from the data buffer.
-	data := make([]byte, s.data.Len())
-	copy(data, s.data.Bytes())
+	data := s.data.Bytes()
 	if data[len(data)-1] == '\n' {
 		data = data[:len(data)-1]
 	}
@@ -235,19 +774,131 @@ func (s Stream) dispatch() {
 	// of the origin of the event stream's final URL (i.e. the URL after redirects), and the lastEventId attribute must be initialized
 	// to the last event ID string of the event source. This event is not trusted.
 	event := Event{
-		Type: "message",
-		Data: string(data),
+		Type:    "message",
+		Data:    s.eventData(data),
+		ID:      s.lastEventID.String(),
+		Version: *s.eventVersion,
 	}
 
 	// 5. If the event type buffer has a value other than the empty string, change the type of the newly created event to equal the value of the event type buffer.
 	if s.eventType.Len() != 0 {
-		event.Type = s.eventType.String()
+		event.Type = s.eventTypeString()
 	}
 
 	// 6. Set the data buffer and the event type buffer to the empty string.
-	s.data.Reset()
-	s.eventType.Reset()
+	hasID := *s.eventHasID
+	s.resetEvent()
+
+	if !s.inVersionRange(event.Version) {
+		return nil
+	}
 
 	// 7. Queue a task which, if the readyState attribute is set to a value other than CLOSED, dispatches the newly created event at the EventSource object.
-	s.events <- event
+	return s.deliver(event, hasID)
+}
+
+// eventTypeString converts the contents of the event type buffer to a string,
+// reusing the string of the previous event's type if it's the same
+func (s Stream) eventTypeString() string {
+	if *s.lastEventType != string(s.eventType.Bytes()) {
+		*s.lastEventType = s.eventType.String()
+	}
+	return *s.lastEventType
+}
+
+// eventData converts the contents of the data buffer to a string
+func (s Stream) eventData(data []byte) string {
+	if !s.zeroCopy || len(data) == 0 {
+		return string(data)
+	}
+
+	// Hand the memory of the data buffer to the event and give the stream a new buffer sized for an event of the same length,
+	// so the string is never modified.
+	str := unsafe.String(&data[0], len(data))
+	*s.data = *bytes.NewBuffer(make([]byte, 0, s.data.Len()))
+	return str
+}
+
+// deliver dispatches event, hasID is whether it has its own ID rather than the one of an earlier event
+func (s Stream) deliver(event Event, hasID bool) error {
+	if s.gaps != nil {
+		if gap := s.gaps.check(event.ID); gap != nil {
+			s.reportError(gap)
+		}
+	}
+
+	if s.aead != nil {
+		data, err := encryptData(s.aead, event.Data)
+		if err != nil {
+			return err
+		}
+		event.Data = data
+	}
+
+	s.stats.eventReceived(s.clock.Now())
+	s.debug("dispatching event", slog.String("event_type", event.Type), slog.String("id", event.ID))
+	if s.tracer != nil {
+		var end func()
+		event.Ctx, end = s.tracer.Event(*s.connectionCtx, event)
+		defer end()
+	}
+
+	var err error
+	if sub := s.subscription(event.Type); sub != nil {
+		err = sub.deliver(s, event)
+	} else {
+		var events chan<- Event = s.events
+		if s.route != nil {
+			events = s.route(event, s.lastEventID.String())
+		}
+		err = s.send(events, event, nil)
+	}
+	if err == ErrDispatchTimeout {
+		return err
+	} else if err != nil {
+		// The event was dropped
+		return nil
+	}
+
+	if s.acker != nil && hasID && event.ID != "" {
+		s.acker.ack(event.ID)
+	}
+
+	if s.lastEventIDStore != nil {
+		if err := s.lastEventIDStore.Store(event, s.lastEventID.String()); err != nil {
+			return errors.Wrap(err, "storing last event id")
+		}
+	}
+
+	return nil
+}
+
+// send sends an event to events unless done is closed first
+func (s Stream) send(events chan<- Event, event Event, done <-chan struct{}) error {
+	// Disabled timeouts are nil channels, which never receive
+	var processingTimeout, dispatchTimeout <-chan time.Time
+	if s.eventProcessingTimeout > 0 {
+		var stop func()
+		processingTimeout, stop = newTimer(s.clock, s.eventProcessingTimeout)
+		defer stop()
+	}
+	if s.dispatchTimeout > 0 {
+		var stop func()
+		dispatchTimeout, stop = newTimer(s.clock, s.dispatchTimeout)
+		defer stop()
+	}
+
+	select {
+	case events <- event:
+		return nil
+	case <-processingTimeout:
+		s.eventProcessingTimeouts.Add(1)
+		return ErrEventProcessingTimeout
+	case <-dispatchTimeout:
+		return ErrDispatchTimeout
+	case <-done:
+		return errUnsubscribed
+	case <-s.ctx.Done():
+		return context.Cause(s.ctx)
+	}
 }
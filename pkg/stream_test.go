@@ -1,11 +1,22 @@
 package sse
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/iotest"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -64,6 +75,7 @@ data:  third event
 				{
 					Type: "message",
 					Data: "first event",
+					ID:   "1",
 				},
 				{
 					Type: "message",
@@ -95,6 +107,38 @@ data:`,
 				},
 			},
 		},
+		{
+			name:  "CR line endings at the end of the stream",
+			input: "data: a\r\rdata: b\r\r",
+			expectedEvents: []Event{
+				{
+					Type: "message",
+					Data: "a",
+				},
+				{
+					Type: "message",
+					Data: "b",
+				},
+			},
+		},
+		{
+			name:  "incomplete event at the end of the stream is discarded",
+			input: "data: foo\r\n",
+		},
+		{
+			name:  "empty value",
+			input: "data:\n\nevent:\ndata: x\n\n",
+			expectedEvents: []Event{
+				{
+					Type: "message",
+					Data: "",
+				},
+				{
+					Type: "message",
+					Data: "x",
+				},
+			},
+		},
 		{
 			name: "leading space is removed",
 			input: `data:test
@@ -128,12 +172,8 @@ data: test
 	runTestCase := func(tc testCase) func(*testing.T) {
 		return func(t *testing.T) {
 			r := ioutil.NopCloser(strings.NewReader(tc.input))
-			s := Stream{
-				events:      make(chan Event, len(tc.expectedEvents)),
-				data:        new(bytes.Buffer),
-				eventType:   new(bytes.Buffer),
-				lastEventID: new(bytes.Buffer),
-			}
+			s := newStream("", WithMaxReconnectAttempts(0))
+			s.events = make(chan Event, len(tc.expectedEvents))
 			require.NoError(s.parse(r))
 
 			var actualEvents []Event
@@ -150,8 +190,290 @@ data: test
 	}
 }
 
+func TestEventEqual(t *testing.T) {
+	event := Event{Type: "score", Data: "1", ID: "7", Version: 2}
+	assert.True(t, event.Equal(event))
+
+	withCtx := event
+	withCtx.Ctx = context.Background()
+	assert.True(t, event.Equal(withCtx))
+
+	for _, other := range []Event{
+		{Type: "message", Data: "1", ID: "7", Version: 2},
+		{Type: "score", Data: "2", ID: "7", Version: 2},
+		{Type: "score", Data: "1", ID: "8", Version: 2},
+		{Type: "score", Data: "1", ID: "7", Version: 3},
+	} {
+		assert.False(t, event.Equal(other), other)
+	}
+
+	assert.True(t, EventsEqual(nil, []Event{}))
+	assert.True(t, EventsEqual([]Event{event, {Data: "2"}}, []Event{withCtx, {Data: "2"}}))
+	assert.False(t, EventsEqual([]Event{event, {Data: "2"}}, []Event{{Data: "2"}, event}))
+	assert.False(t, EventsEqual([]Event{event}, []Event{event, event}))
+}
+
+func TestNewWithContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(time.Millisecond):
+				w.Write([]byte("data: tick\n\n"))
+				w.(http.Flusher).Flush()
+			}
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	closed := make(chan error, 1)
+	s, err := NewWithContext(ctx, server.URL, WithStateChangeHook(func(from, to ReadyState, err error) {
+		if to == Closed {
+			closed <- err
+		}
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "tick", (<-s.Events()).Data)
+	cancel()
+	for range s.Events() {
+	}
+	assert.Equal(t, context.Canceled, <-closed)
+	assert.Equal(t, Closed, s.State())
+}
+
+func TestStreamClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(time.Millisecond):
+				w.Write([]byte("data: tick\n\n"))
+				w.(http.Flusher).Flush()
+			}
+		}
+	}))
+	defer server.Close()
+
+	s, err := New(server.URL, WithChannelBuffer(8))
+	require.NoError(t, err)
+	assert.Equal(t, "tick", (<-s.Events()).Data)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, s.Close())
+		}()
+	}
+	wg.Wait()
+
+	_, ok := <-s.Events()
+	assert.False(t, ok)
+	assert.Equal(t, Closed, s.State())
+	assert.Equal(t, ErrStreamClosed, context.Cause(s.ctx))
+}
+
+func TestStreamCloseFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	s, err := New(server.URL)
+	require.Error(t, err)
+	assert.NoError(t, s.Close())
+}
+
+func TestStreamChainContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(time.Millisecond):
+				w.Write([]byte("data: tick\n\n"))
+				w.(http.Flusher).Flush()
+			}
+		}
+	}))
+	defer server.Close()
+
+	s, err := New(server.URL)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	s.ChainContext(ctx)
+
+	assert.Equal(t, "tick", (<-s.Events()).Data)
+	cancel(ErrStreamTimeout)
+	for range s.Events() {
+	}
+	assert.Equal(t, Closed, s.State())
+	assert.Equal(t, ErrStreamTimeout, context.Cause(s.ctx))
+}
+
 func TestStreamInvalidUTF8(t *testing.T) {
 	r := ioutil.NopCloser(strings.NewReader("\x80"))
-	s := Stream{events: make(chan Event)}
+	s := newStream("", WithMaxReconnectAttempts(0))
 	assert.Error(t, s.parse(r))
 }
+
+func TestStreamZeroCopy(t *testing.T) {
+	s := newStream("", WithZeroCopy())
+	s.events = make(chan Event, 2)
+
+	s.process(dataType, []byte("first"))
+	s.dispatch()
+	s.process(dataType, []byte("second"))
+	s.dispatch()
+
+	assert.Equal(t, "first", (<-s.events).Data)
+	assert.Equal(t, "second", (<-s.events).Data)
+}
+
+func BenchmarkDispatch(b *testing.B) {
+	data := bytes.Repeat([]byte("x"), 1024)
+
+	benchmark := func(opts ...Option) func(*testing.B) {
+		return func(b *testing.B) {
+			s := newStream("", opts...)
+			s.events = make(chan Event, 1)
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				s.process(dataType, data)
+				s.dispatch()
+				<-s.events
+			}
+		}
+	}
+
+	b.Run("copy", benchmark())
+	b.Run("zero copy", benchmark(WithZeroCopy()))
+}
+
+func TestStreamConcurrentReaders(t *testing.T) {
+	t.Parallel()
+
+	const events = 1000
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < events; i++ {
+			w.Write([]byte("data: " + strconv.Itoa(i) + "\n\n"))
+		}
+	}))
+	defer server.Close()
+
+	s, err := New(server.URL, WithMaxReconnectAttempts(0))
+	require.NoError(t, err)
+
+	// Readers share the channel, so each event is received by exactly one of them
+	received := make([][]string, 2)
+	var wg sync.WaitGroup
+	for i := range received {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for event := range s.Events() {
+				received[i] = append(received[i], event.Data)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for _, data := range append(received[0], received[1]...) {
+		assert.False(t, seen[data], "received %s twice", data)
+		seen[data] = true
+	}
+	assert.Len(t, seen, events)
+}
+
+func TestParseAll(t *testing.T) {
+	events, err := ParseAll(strings.NewReader("id: 1\nevent: score\ndata: a\n\n: comment\ndata: b\ndata: c\n\ndata: incomplete\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []Event{{Type: "score", Data: "a", ID: "1"}, {Type: "message", Data: "b\nc", ID: "1"}}, events)
+
+	events, err = ParseAll(strings.NewReader("data: a\n\ndata: b\n"), WithDispatchOnEOF())
+	require.NoError(t, err)
+	assert.Equal(t, []Event{{Type: "message", Data: "a"}, {Type: "message", Data: "b"}}, events)
+
+	events, err = ParseAll(io.MultiReader(strings.NewReader("data: a\n\n"), iotest.ErrReader(errors.New("broken"))))
+	assert.EqualError(t, err, "broken")
+	assert.Equal(t, []Event{{Type: "message", Data: "a"}}, events)
+}
+
+func TestWithDispatchOnEOF(t *testing.T) {
+	for _, input := range []string{"data: foo\n", "data: foo\r\n", "data: foo\n\n"} {
+		s := newStream("", WithMaxReconnectAttempts(0), WithDispatchOnEOF())
+		s.events = make(chan Event, 2)
+		require.NoError(t, s.parse(ioutil.NopCloser(strings.NewReader(input))))
+
+		var events []Event
+		for event := range s.Events() {
+			events = append(events, event)
+		}
+		assert.Equal(t, []Event{{Type: "message", Data: "foo"}}, events, input)
+	}
+}
+
+func TestStreamDiscardsIncompleteEventOnReconnect(t *testing.T) {
+	var connections atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if connections.Add(1) == 1 {
+			w.Write([]byte("retry: 1\nevent: partial\ndata: a\n"))
+			return
+		}
+		w.Write([]byte("data: b\n\n"))
+	}))
+	defer server.Close()
+
+	s, err := New(server.URL, WithMaxReconnectAttempts(1))
+	require.NoError(t, err)
+	assert.Equal(t, Event{Type: "message", Data: "b"}, <-s.Events())
+	require.NoError(t, s.Close())
+}
+
+func TestWithScannerBufferSize(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	long := strings.Repeat("x", 100*1024)
+	s := newStream("", WithMaxReconnectAttempts(0))
+	s.events = make(chan Event, 1)
+	require.NoError(s.parse(ioutil.NopCloser(strings.NewReader("data: " + long + "\n\n"))))
+	assert.Equal(long, (<-s.Events()).Data)
+
+	s = newStream("", WithMaxReconnectAttempts(0), WithScannerBufferSize(16, 64))
+	assert.ErrorIs(s.parse(ioutil.NopCloser(strings.NewReader("data: "+long+"\n\n"))), bufio.ErrTooLong)
+
+	s = newStream("", WithScannerBufferSize(64, 16))
+	assert.EqualError(s.optionErr, "invalid scanner buffer size 64 up to 16")
+}
+
+func BenchmarkParse(b *testing.B) {
+	benchmark := func(events int) func(*testing.B) {
+		var input bytes.Buffer
+		for i := 0; i < events; i++ {
+			input.WriteString("id: " + strconv.Itoa(i) + "\nevent: score\ndata: " + strconv.Itoa(i) + "\n\n")
+		}
+
+		return func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				s := newStream("", WithMaxReconnectAttempts(0))
+				s.events = make(chan Event, events)
+				if err := s.parse(ioutil.NopCloser(bytes.NewReader(input.Bytes()))); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+
+	b.Run("10k events", benchmark(10000))
+	b.Run("100k events", benchmark(100000))
+}
@@ -0,0 +1,137 @@
+package sse
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// errUnsubscribed is returned when an event is dropped because its subscription was closed while it was being sent
+var errUnsubscribed = errors.New("unsubscribed")
+
+// subscriptions holds the channels of event types subscribed to with Stream.Subscribe, it's shared between copies of a Stream
+type subscriptions struct {
+	mu     sync.Mutex
+	byType map[string]*subscription
+	// closed is set once the stream closes, later subscriptions are closed immediately
+	closed bool
+}
+
+type subscription struct {
+	events chan Event
+	// done is closed to stop sends to events before it's closed
+	done chan struct{}
+
+	// mu is held while sending to events so it isn't closed during a send
+	mu     sync.Mutex
+	closed bool
+}
+
+// Subscribe returns a channel receiving only the events with a type.
+// Calling it again with the same type returns the same channel.
+//
+// Events of a subscribed type are sent to its channel instead of the channel returned by Events,
+// from the time Subscribe is called. With "*" or "" the channel returned by Events is returned.
+// The channel is closed by Unsubscribe or when the stream closes.
+func (s Stream) Subscribe(eventType string) <-chan Event {
+	events, _ := s.subscribe(eventType)
+	return events
+}
+
+// subscribe returns the channel of a subscription to an event type, reporting whether the subscription is new
+func (s Stream) subscribe(eventType string) (<-chan Event, bool) {
+	if eventType == "" || eventType == "*" {
+		return s.events, false
+	}
+
+	s.subscriptions.mu.Lock()
+	defer s.subscriptions.mu.Unlock()
+
+	if sub, ok := s.subscriptions.byType[eventType]; ok {
+		return sub.events, false
+	}
+	sub := &subscription{events: make(chan Event), done: make(chan struct{})}
+	if s.subscriptions.closed {
+		sub.close()
+		return sub.events, false
+	}
+	s.subscriptions.byType[eventType] = sub
+	return sub.events, true
+}
+
+// Once waits for the first event with a type, see Subscribe.
+//
+// The subscription to the type is removed afterwards unless it existed before Once was called.
+// It fails with ctx's error if ctx is done first, or with the reason the stream closed if it closes first.
+func (s Stream) Once(ctx context.Context, eventType string) (Event, error) {
+	events, subscribed := s.subscribe(eventType)
+	if subscribed {
+		defer s.Unsubscribe(eventType)
+	}
+
+	select {
+	case event, ok := <-events:
+		if !ok {
+			return Event{}, context.Cause(s.ctx)
+		}
+		return event, nil
+	case <-ctx.Done():
+		return Event{}, ctx.Err()
+	}
+}
+
+// Unsubscribe closes the channel returned by Subscribe for a type.
+// Events of the type are sent to the channel returned by Events again.
+func (s Stream) Unsubscribe(eventType string) {
+	s.subscriptions.mu.Lock()
+	sub, ok := s.subscriptions.byType[eventType]
+	delete(s.subscriptions.byType, eventType)
+	s.subscriptions.mu.Unlock()
+
+	if ok {
+		sub.close()
+	}
+}
+
+// subscription returns the subscription to an event type, or nil if there isn't one
+func (s Stream) subscription(eventType string) *subscription {
+	s.subscriptions.mu.Lock()
+	defer s.subscriptions.mu.Unlock()
+
+	return s.subscriptions.byType[eventType]
+}
+
+// closeSubscriptions closes every subscription when the stream closes
+func (s Stream) closeSubscriptions() {
+	s.subscriptions.mu.Lock()
+	byType := s.subscriptions.byType
+	s.subscriptions.byType = nil
+	s.subscriptions.closed = true
+	s.subscriptions.mu.Unlock()
+
+	for _, sub := range byType {
+		sub.close()
+	}
+}
+
+// deliver sends an event to the subscription, failing if it's closed first
+func (sub *subscription) deliver(s Stream, event Event) error {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	if sub.closed {
+		return errUnsubscribed
+	}
+	return s.send(sub.events, event, sub.done)
+}
+
+func (sub *subscription) close() {
+	close(sub.done)
+
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	sub.closed = true
+	close(sub.events)
+}
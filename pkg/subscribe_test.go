@@ -0,0 +1,88 @@
+package sse
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamSubscribe(t *testing.T) {
+	s := newStream("", WithMaxReconnectAttempts(0))
+	scores := s.Subscribe("score")
+	assert.Equal(t, scores, s.Subscribe("score"))
+	assert.Equal(t, s.Events(), s.Subscribe("*"))
+	assert.Equal(t, s.Events(), s.Subscribe(""))
+
+	go s.parse(ioutil.NopCloser(strings.NewReader("event: score\ndata: 1\n\nevent: goal\ndata: 2\n\nevent: score\ndata: 3\n\n")))
+
+	var wg sync.WaitGroup
+	collect := func(events <-chan Event, data *[]string) {
+		defer wg.Done()
+		for event := range events {
+			*data = append(*data, event.Data)
+		}
+	}
+	var scoreData, otherData []string
+	wg.Add(2)
+	go collect(scores, &scoreData)
+	go collect(s.Events(), &otherData)
+	wg.Wait()
+
+	assert.Equal(t, []string{"1", "3"}, scoreData)
+	assert.Equal(t, []string{"2"}, otherData)
+
+	_, ok := <-s.Subscribe("goal")
+	assert.False(t, ok)
+}
+
+func TestStreamUnsubscribe(t *testing.T) {
+	s := newStream("", WithMaxReconnectAttempts(0))
+	scores := s.Subscribe("score")
+
+	r, w := io.Pipe()
+	go s.parse(r)
+
+	go w.Write([]byte("event: score\ndata: 1\n\n"))
+	assert.Equal(t, "1", (<-scores).Data)
+
+	s.Unsubscribe("score")
+	_, ok := <-scores
+	assert.False(t, ok)
+	s.Unsubscribe("score")
+
+	go func() {
+		w.Write([]byte("event: score\ndata: 2\n\n"))
+		w.Close()
+	}()
+	assert.Equal(t, "2", (<-s.Events()).Data)
+}
+
+func TestStreamOnce(t *testing.T) {
+	s := newStream("", WithMaxReconnectAttempts(0))
+	s.events = make(chan Event, 1)
+
+	r, w := io.Pipe()
+	go s.parse(r)
+	go w.Write([]byte("event: goal\ndata: 1\n\nevent: score\ndata: 2\n\n"))
+
+	event, err := s.Once(context.Background(), "score")
+	require.NoError(t, err)
+	assert.Equal(t, "2", event.Data)
+	assert.Nil(t, s.subscription("score"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = s.Once(ctx, "score")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	w.Close()
+	_, err = s.Once(context.Background(), "score")
+	assert.ErrorIs(t, err, context.Canceled)
+}
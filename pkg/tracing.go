@@ -0,0 +1,24 @@
+package sse
+
+import "context"
+
+// Tracer traces a stream's connection attempts and the events received on them.
+//
+// The otelsse package implements it with OpenTelemetry.
+type Tracer interface {
+	// Connect is called before an attempt to connect to resource, attempt is 0 for the initial connection.
+	// The returned function is called with the status code of the response, which is 0 if there isn't one,
+	// and the error if the attempt failed. Events received on the connection are traced with the returned context.
+	Connect(ctx context.Context, resource string, attempt int) (context.Context, func(status int, err error))
+	// Event is called when an event is dispatched, and the returned function once it's been received.
+	// The returned context is set as the event's Ctx.
+	Event(ctx context.Context, event Event) (context.Context, func())
+}
+
+// WithTracer traces the stream's connections and events with t
+func WithTracer(t Tracer) Option {
+	return func(s *Stream) {
+		s.tracer = t
+		s.connectionCtx = new(context.Context)
+	}
+}
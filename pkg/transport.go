@@ -0,0 +1,140 @@
+package sse
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// HTTPVersion is the version of HTTP used to connect to a resource
+type HTTPVersion int
+
+const (
+	// HTTP1 is HTTP/1.0. Requests are sent as HTTP/1.1, which HTTP/1.0 servers accept.
+	HTTP1 HTTPVersion = iota + 1
+	// HTTP11 is HTTP/1.1
+	HTTP11
+	// HTTP2 is HTTP/2. It is only negotiated for https resources.
+	HTTP2
+)
+
+func (v HTTPVersion) String() string {
+	switch v {
+	case HTTP1:
+		return "HTTP/1.0"
+	case HTTP11:
+		return "HTTP/1.1"
+	case HTTP2:
+		return "HTTP/2"
+	}
+	return "unknown"
+}
+
+func (v HTTPVersion) protoMajor() int {
+	switch v {
+	case HTTP2:
+		return 2
+	}
+	return 1
+}
+
+// WithHTTPVersion configures the transport of the stream to use a specific version of HTTP
+//
+// A warning is logged to the logger set by WithLogger if the server responds with a different version.
+func WithHTTPVersion(v HTTPVersion) Option {
+	return func(s *Stream) {
+		switch v {
+		case HTTP1, HTTP11:
+			t := s.httpTransport()
+			t.ForceAttemptHTTP2 = false
+			// A non-nil empty map disables HTTP/2
+			t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		case HTTP2:
+			s.httpTransport().ForceAttemptHTTP2 = true
+		default:
+			s.optionErr = errors.Errorf("unknown http version %d", v)
+			return
+		}
+		s.httpVersion = v
+	}
+}
+
+// WithHTTPClient makes requests for the stream with c instead of http.DefaultClient
+//
+// Options configuring the transport of the stream, like WithDialer, modify a copy of c's transport if they're applied after it,
+// and are discarded if they're applied before it. They can only be applied after it if c's transport is nil or an *http.Transport.
+func WithHTTPClient(c *http.Client) Option {
+	return func(s *Stream) {
+		s.httpClient = c
+		s.transport = nil
+	}
+}
+
+// WithTransport makes requests for the stream with rt, without modifying the http client it's given or http.DefaultTransport
+//
+// Like WithHTTPClient, options configuring the transport of the stream modify a copy of rt if they're applied after it,
+// and are discarded if they're applied before it. Applying them after an rt that isn't an *http.Transport is an error,
+// since they'd have to replace it.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(s *Stream) {
+		client := *s.httpClient
+		client.Transport = rt
+		s.httpClient = &client
+		s.transport = nil
+	}
+}
+
+// WithTLSConfig configures the transport of the stream to use a copy of c, for example to trust self-signed certificates
+// or present a client certificate for mutual TLS. It's an error if the stream's transport isn't an *http.Transport.
+func WithTLSConfig(c *tls.Config) Option {
+	return func(s *Stream) {
+		s.httpTransport().TLSClientConfig = c.Clone()
+	}
+}
+
+// WithDialer configures the transport of the stream to open connections with d.
+// It's an error if the stream's transport isn't an *http.Transport.
+func WithDialer(d *net.Dialer) Option {
+	return func(s *Stream) {
+		s.httpTransport().DialContext = d.DialContext
+	}
+}
+
+// httpTransport returns a transport owned by the stream.
+// The first time it's called the http client is replaced with a copy using a copy of its transport, or of http.DefaultTransport.
+//
+// If the http client's transport isn't an *http.Transport the option error is set, since it can't be configured.
+func (s *Stream) httpTransport() *http.Transport {
+	if s.transport == nil {
+		rt := s.httpClient.Transport
+		if rt == nil {
+			rt = http.DefaultTransport
+		}
+		base, ok := rt.(*http.Transport)
+		if !ok {
+			s.optionErr = errors.Errorf("transport options require an *http.Transport, not %T", rt)
+			// The options configure a transport that's never used, the stream isn't created
+			return &http.Transport{}
+		}
+		s.transport = base.Clone()
+
+		client := *s.httpClient
+		client.Transport = s.transport
+		s.httpClient = &client
+	}
+	return s.transport
+}
+
+func (s Stream) checkHTTPVersion(resp *http.Response) {
+	if s.httpVersion == 0 || resp.ProtoMajor == s.httpVersion.protoMajor() {
+		return
+	}
+	s.warn("http version mismatch",
+		slog.String("resource", resp.Request.URL.String()),
+		slog.String("requested", s.httpVersion.String()),
+		slog.String("proto", resp.Proto),
+	)
+}
@@ -0,0 +1,172 @@
+package sse
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithHTTPVersion(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	for _, version := range []HTTPVersion{HTTP11, HTTP2} {
+		logs.Reset()
+		s := newStream(server.URL, WithHTTPVersion(version), WithLogger(logger))
+		assert.NotSame(http.DefaultTransport, s.transport)
+		s.transport.TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig
+
+		r, err := s.connect(0)
+		require.NoError(err)
+		r.Close()
+		assert.NotContains(logs.String(), "http version mismatch", version.String())
+	}
+
+	// The server only speaks HTTP/1.1 without TLS
+	plain := httptest.NewServer(server.Config.Handler)
+	defer plain.Close()
+	r, err := newStream(plain.URL, WithHTTPVersion(HTTP2), WithLogger(logger)).connect(0)
+	require.NoError(err)
+	r.Close()
+	assert.Contains(logs.String(), `level=WARN msg="http version mismatch"`)
+	assert.Contains(logs.String(), "requested=HTTP/2 proto=HTTP/1.1")
+}
+
+func TestWithHTTPVersionUnsupported(t *testing.T) {
+	_, err := New("http://localhost", WithHTTPVersion(HTTP2+1))
+	assert.EqualError(t, err, "unknown http version 4")
+}
+
+func TestWithDialer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var dialed string
+	dialer := &net.Dialer{
+		Control: func(network, address string, c syscall.RawConn) error {
+			dialed = address
+			return nil
+		},
+	}
+
+	r, err := newStream(server.URL, WithDialer(dialer)).connect(0)
+	require.NoError(t, err)
+	r.Close()
+
+	assert.Equal(t, server.Listener.Addr().String(), dialed)
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	client := server.Client()
+	transport := client.Transport.(*http.Transport)
+
+	r, err := newStream(server.URL, WithHTTPClient(client)).connect(0)
+	require.NoError(t, err)
+	r.Close()
+
+	var dialed bool
+	dialer := &net.Dialer{
+		Control: func(network, address string, c syscall.RawConn) error {
+			dialed = true
+			return nil
+		},
+	}
+	s := newStream(server.URL, WithHTTPClient(client), WithDialer(dialer))
+	r, err = s.connect(0)
+	require.NoError(t, err)
+	r.Close()
+
+	assert.True(t, dialed)
+	assert.Same(t, transport, client.Transport)
+	assert.NotSame(t, transport, s.transport)
+}
+
+func TestWithTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := newStream(server.URL).connect(0)
+	require.Error(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	config := &tls.Config{RootCAs: roots}
+	r, err := newStream(server.URL, WithTLSConfig(config)).connect(0)
+	require.NoError(t, err)
+	r.Close()
+
+	if c := http.DefaultTransport.(*http.Transport).TLSClientConfig; c != nil {
+		assert.Nil(t, c.RootCAs)
+	}
+	assert.Empty(t, config.NextProtos)
+}
+
+func TestWithTransport(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	transport := server.Client().Transport
+
+	s := newStream(server.URL, WithTransport(transport))
+	r, err := s.connect(0)
+	require.NoError(t, err)
+	r.Close()
+
+	assert.Same(t, http.DefaultClient, newStream("").httpClient)
+	assert.Nil(t, http.DefaultClient.Transport)
+	assert.Equal(t, transport, s.httpClient.Transport)
+}
+
+// roundTripperFunc is a RoundTripper that isn't an *http.Transport
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestWithTransportCustomRoundTripper(t *testing.T) {
+	rt := roundTripperFunc(http.DefaultTransport.RoundTrip)
+
+	_, err := New("http://localhost", WithTransport(rt), WithHTTPVersion(HTTP2))
+	assert.EqualError(t, err, "transport options require an *http.Transport, not sse.roundTripperFunc")
+
+	_, err = New("http://localhost", WithTransport(rt), WithTLSConfig(&tls.Config{}))
+	assert.EqualError(t, err, "transport options require an *http.Transport, not sse.roundTripperFunc")
+
+	_, err = New("http://localhost", WithHTTPClient(&http.Client{Transport: rt}), WithDialer(&net.Dialer{}))
+	assert.EqualError(t, err, "transport options require an *http.Transport, not sse.roundTripperFunc")
+
+	// The transport options are discarded if they're applied first
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	var used bool
+	s := newStream(server.URL, WithTLSConfig(&tls.Config{}), WithTransport(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		used = true
+		return http.DefaultTransport.RoundTrip(r)
+	})))
+	require.NoError(t, s.optionErr)
+	r, err := s.connect(0)
+	require.NoError(t, err)
+	r.Close()
+	assert.True(t, used)
+}
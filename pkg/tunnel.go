@@ -0,0 +1,51 @@
+package sse
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// tunnelReconnectionTime is the longest a stream waits to reconnect through a tunnel
+const tunnelReconnectionTime = 500 * time.Millisecond
+
+// WithTunnelDetection detects whether the stream is connected through a tunnel or reverse proxy, such as ngrok,
+// from the X-Forwarded-Proto, X-Forwarded-For and ngrok-* headers of the response.
+//
+// Tunnels come back quickly, so once one is detected the stream reconnects after at most 500ms, without exponential backoff,
+// unless WithReconnectInterval is set. A line with tunnel_detected=true is logged to the logger set by WithLogger
+// the first time one is detected.
+// The result is reported by Stream.IsTunneled.
+func WithTunnelDetection() Option {
+	return func(s *Stream) {
+		s.tunnelDetection = true
+	}
+}
+
+// IsTunneled reports whether WithTunnelDetection detected a tunnel
+func (s Stream) IsTunneled() bool {
+	return s.tunneled.Load()
+}
+
+// detectTunnel records whether resp came through a tunnel
+func (s Stream) detectTunnel(resp *http.Response) {
+	if !isTunneled(resp.Header) {
+		return
+	}
+	if s.tunneled.CompareAndSwap(false, true) {
+		s.debug("tunnel detected", slog.Bool("tunnel_detected", true), slog.String("resource", resp.Request.URL.String()))
+	}
+}
+
+func isTunneled(header http.Header) bool {
+	if header.Get("X-Forwarded-Proto") != "" || header.Get("X-Forwarded-For") != "" {
+		return true
+	}
+	for name := range header {
+		if strings.HasPrefix(strings.ToLower(name), "ngrok-") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package sse
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsTunneled(t *testing.T) {
+	assert.False(t, isTunneled(http.Header{"Content-Type": {"text/event-stream"}}))
+	assert.True(t, isTunneled(http.Header{"X-Forwarded-Proto": {"https"}}))
+	assert.True(t, isTunneled(http.Header{"X-Forwarded-For": {"203.0.113.1"}}))
+	assert.True(t, isTunneled(http.Header{"Ngrok-Agent-Ips": {"203.0.113.1"}}))
+}
+
+func TestWithTunnelDetection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Ngrok-Trace-Id", "abc")
+		w.Write([]byte("data: a\n\n"))
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	s, err := New(server.URL, WithTunnelDetection(), WithMaxReconnectAttempts(0), WithLogger(logger))
+	require.NoError(t, err)
+	for range s.Events() {
+	}
+
+	assert.True(t, s.IsTunneled())
+	assert.Contains(t, logs.String(), `msg="tunnel detected" tunnel_detected=true resource=`+server.URL)
+	assert.Equal(t, tunnelReconnectionTime, s.reconnectDelay(1))
+	assert.Equal(t, tunnelReconnectionTime, s.reconnectDelay(5))
+
+	s = newStream("", WithReconnectionTime(100*time.Millisecond))
+	s.tunneled.Store(true)
+	assert.Equal(t, 100*time.Millisecond, s.reconnectDelay(5))
+}
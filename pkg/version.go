@@ -0,0 +1,21 @@
+package sse
+
+// WithMinVersion drops events with a version field lower than v.
+// Events without a version field have version 0.
+func WithMinVersion(v int) Option {
+	return func(s *Stream) {
+		s.minVersion = v
+	}
+}
+
+// WithMaxVersion drops events with a version field higher than v
+func WithMaxVersion(v int) Option {
+	return func(s *Stream) {
+		s.maxVersion = v
+	}
+}
+
+// inVersionRange reports whether events with a version are supported by the stream
+func (s Stream) inVersionRange(version int) bool {
+	return version >= s.minVersion && version <= s.maxVersion
+}
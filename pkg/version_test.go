@@ -0,0 +1,39 @@
+package sse
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamVersion(t *testing.T) {
+	input := "version: 1\ndata: a\n\nversion: 2\ndata: b\n\nversion: x\ndata: c\n\nversion: 3\ndata: d\n\n"
+
+	parse := func(opts ...Option) []Event {
+		s := newStream("", append(opts, WithMaxReconnectAttempts(0))...)
+		s.events = make(chan Event, 4)
+		require.NoError(t, s.parse(ioutil.NopCloser(strings.NewReader(input))))
+
+		var events []Event
+		for event := range s.events {
+			events = append(events, event)
+		}
+		return events
+	}
+
+	assert.Equal(t, []Event{
+		{Type: "message", Data: "a", Version: 1},
+		{Type: "message", Data: "b", Version: 2},
+		{Type: "message", Data: "c"},
+		{Type: "message", Data: "d", Version: 3},
+	}, parse())
+
+	var data []string
+	for _, event := range parse(WithMinVersion(2), WithMaxVersion(2)) {
+		data = append(data, event.Data)
+	}
+	assert.Equal(t, []string{"b"}, data)
+}